@@ -1,10 +1,22 @@
 package app
 
 import (
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/app/ui/add"
+	"github.com/danielroehrig/timekeeper/app/ui/away"
 	"github.com/danielroehrig/timekeeper/app/ui/editor"
 	l "github.com/danielroehrig/timekeeper/app/ui/list"
+	"github.com/danielroehrig/timekeeper/app/ui/notify"
+	"github.com/danielroehrig/timekeeper/app/ui/report"
 	"github.com/danielroehrig/timekeeper/app/ui/task"
+	"github.com/danielroehrig/timekeeper/backup"
+	"github.com/danielroehrig/timekeeper/export"
+	"github.com/danielroehrig/timekeeper/idle"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
@@ -12,11 +24,11 @@ import (
 	"github.com/charmbracelet/bubbles/stopwatch"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/config"
 	dbaccess "github.com/danielroehrig/timekeeper/db"
 	"github.com/danielroehrig/timekeeper/log"
 	"github.com/danielroehrig/timekeeper/models"
 	"github.com/danielroehrig/timekeeper/themes"
-	"github.com/ostafen/clover/v2"
 )
 
 type Focused byte
@@ -25,20 +37,41 @@ const (
 	Task Focused = iota
 	EntryList
 	Editor
+	Report
+	Add
 )
 
 type model struct {
-	db          *clover.DB
+	db          dbaccess.EntryStore
 	focused     Focused
 	runningTask *models.Entry
 	dirtyTask   *models.Entry
+	dirtyEdit   editor.Edit
 	stopwatch   stopwatch.Model
 	task        task.Model
 	entryList   l.Model
 	editor      editor.Model
+	report      report.Model
+	add         add.Model
 	theme       themes.Theme
+	themeName   string
+	keys        keys.KeyMap
+	showHelp    bool
+	showHistory bool
+	quitting    bool // quit was pressed once although changes could not be saved
+	notify      notify.Model
 	width       int
 	height      int
+	backup      backup.Options
+
+	away          away.Model
+	showAway      bool
+	awaySince     time.Time // start of the idle time of the running task, zero while active
+	lastActivity  time.Time
+	idleThreshold time.Duration
+	idleSource    idle.Source // nil when only keys in the TUI count
+	// startup holds problems found before the TUI started, shown once it runs
+	startup []error
 }
 
 type AddEntryMsg struct {
@@ -47,23 +80,92 @@ type AddEntryMsg struct {
 type EntryAddedMsg struct{}
 type NextFocusMsg struct{}
 
-func initialModel(db *clover.DB) model {
-	theme := themes.NewTokyoNight()
+// backupMsg checks whether a scheduled backup is due.
+type backupMsg struct{}
+
+// idleCheckMsg looks for idle time of the running task.
+type idleCheckMsg struct{}
+
+// systemIdleMsg carries the idle time reported by the idle command.
+type systemIdleMsg struct {
+	idle time.Duration
+	err  error
+}
+
+// idleCheckInterval is how often idle time is checked.
+const idleCheckInterval = 30 * time.Second
+
+// saveEntryMsg stores an entry again after saving it failed.
+type saveEntryMsg struct {
+	Entry *models.Entry
+}
+
+// saveChangesMsg stores the changes from the editor again after saving them failed.
+type saveChangesMsg struct{}
+
+// switchEntryMsg stores a switch between two entries again after it failed.
+type switchEntryMsg struct {
+	Ended *models.Entry
+	Next  *models.Entry
+}
+
+func initialModel(db dbaccess.EntryStore, cfg config.Config) model {
+	themeName := cfg.Theme
+	if themes.NoColor() {
+		themeName = "monochrome"
+	}
+	theme, ok := themes.ByName(themeName)
+	if !ok {
+		themeName, theme = "tokyonight", themes.NewTokyoNight()
+	}
+	km, err := cfg.KeyMap()
+	if err != nil {
+		log.Warnf("ignoring invalid key bindings: %v", err)
+		km = keys.Default()
+	}
+	layout, rounding, parser := cfg.Layout(), cfg.RoundingRule(), cfg.TimeParser()
+	var source idle.Source
+	if cfg.IdleCommand != "" {
+		source = idle.Command(cfg.IdleCommand)
+	}
 	return model{
 		db:        db,
 		focused:   Task,
-		task:      task.New(theme),
+		task:      task.New(theme, layout, parser, km, cfg.PomodoroSettings(), cfg.Pomodoro.Enabled),
 		stopwatch: stopwatch.New(),
-		entryList: l.New(theme),
-		editor:    editor.New(),
+		entryList: l.New(theme, layout, rounding, km),
+		editor:    editor.New(theme, layout, parser, km),
+		report:    report.New(theme, cfg.FirstWeekday(), rounding, layout, km),
+		add:       add.New(theme, layout, parser, km),
 		theme:     theme,
+		themeName: themeName,
+		keys:      km,
+		notify:    notify.New(theme, km, layout.Time),
+		backup:    cfg.BackupOptions(),
 		width:     10,
 		height:    10,
+
+		away:          away.New(theme, layout, km),
+		lastActivity:  time.Now(),
+		idleThreshold: cfg.IdleThreshold,
+		idleSource:    source,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Sequence(loadEntries(m.db), m.stopwatch.Init(), m.stopwatch.Start(), cursor.Blink, m.task.Init())
+	cmds := []tea.Cmd{loadEntries(m.db), loadProjects(m.db), loadRunning(m.db), m.stopwatch.Init(), m.stopwatch.Start(), cursor.Blink, m.task.Init()}
+	for _, err := range m.startup {
+		cmds = append(cmds, notify.Errorf("%v", err))
+	}
+	// a tick only returns once it fires, in the sequence it would hold up everything after it
+	batch := []tea.Cmd{tea.Sequence(cmds...)}
+	if m.backup.Interval > 0 {
+		batch = append(batch, scheduleBackup())
+	}
+	if m.idleThreshold > 0 {
+		batch = append(batch, scheduleIdleCheck())
+	}
+	return tea.Batch(batch...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -74,13 +176,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeypress(msg)
 	case l.EntriesLoadedMsg:
 		log.Debugf("Received entries from database")
+		m.report, _ = m.report.Update(report.EntriesLoadedMsg{Entries: msg.Entries})
+		m.task, _ = m.task.Update(task.EntriesLoadedMsg{Entries: msg.Entries})
 		m.entryList, cmd = m.entryList.Update(msg)
 		return m, cmd
 	case task.StartRunningMsg:
 		log.Debugf("Starting running task: %v", msg)
+		// a task restored from the database already has an id and keeps the current focus
+		var saved tea.Cmd
+		if msg.RunningTask.ObjectId == "" {
+			// a task typed with an earlier start may reach into the entries before it
+			previous, err := m.overlap(msg.RunningTask, msg.RunningTask.Start, nil)
+			if err != nil {
+				return m, notify.Errorf("%s: %v", msg.RunningTask.Name, err)
+			}
+			if previous != nil && !msg.Trim {
+				msg.Trim = true
+				return m, func() tea.Msg {
+					return task.TrimNeededMsg{Start: msg.RunningTask.Start, Previous: previous, Confirmed: msg}
+				}
+			}
+			if previous != nil {
+				saved = m.trimPrevious(previous, msg.RunningTask, msg.RunningTask.Start)
+			}
+			saved = tea.Batch(saved, m.save(msg.RunningTask))
+			m.focused = Editor
+		}
 		m.runningTask = msg.RunningTask
 		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: msg.RunningTask})
-		m.focused = Editor
+		m.report, _ = m.report.Update(report.AddEntryMsg{Entry: msg.RunningTask})
+		m.task, cmd = m.task.Update(msg)
+		// the entry might have introduced a new project
+		return m, tea.Batch(cmd, saved, loadProjects(m.db))
+	case task.PhaseEndedMsg:
+		return m, m.endPhase(msg)
+	case task.PastEntryMsg:
+		log.Debugf("Adding past entry: %v", msg.Entry)
+		previous, err := m.overlap(msg.Entry, msg.Entry.Start, msg.Entry.End)
+		if err != nil {
+			return m, notify.Errorf("%s: %v", msg.Entry.Name, err)
+		}
+		var trimmed tea.Cmd
+		if previous != nil && !msg.Trim {
+			msg.Trim = true
+			return m, func() tea.Msg {
+				return task.TrimNeededMsg{Start: msg.Entry.Start, Previous: previous, Confirmed: msg}
+			}
+		}
+		if previous != nil {
+			trimmed = m.trimPrevious(previous, msg.Entry, msg.Entry.Start)
+		}
+		m.task, cmd = m.task.Update(msg)
+		return m, tea.Batch(cmd, trimmed, m.storeFinished(msg.Entry), loadProjects(m.db), notify.Infof("added %s, %s", msg.Entry.Name, msg.Entry.Duration(*msg.Entry.End)))
+	case task.ProjectsLoadedMsg:
 		m.task, cmd = m.task.Update(msg)
 		return m, cmd
 	case EntryAddedMsg:
@@ -103,92 +251,458 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(dc, te)
 	case NextFocusMsg:
 		log.Debugf("Next Focus Message received: %d", m.focused)
-		m.saveChanges()
+		saved := m.saveChanges()
 		switch m.focused {
 		case Task:
 			m.focused = EntryList
 		case EntryList:
+			m.focused = Report
+		case Report:
 			if m.runningTask != nil {
 				m.editor, cmd = m.editor.Update(editor.EntryListSelectedMsg{Entry: m.runningTask})
 			}
 			m.focused = Task
-		case Editor:
+		case Editor, Add:
 			if m.runningTask != nil {
 				m.editor, cmd = m.editor.Update(editor.EntryListSelectedMsg{Entry: m.runningTask})
 			}
 			m.focused = Task
 		}
+		return m, tea.Batch(cmd, saved)
 	case task.StopRunningTaskMsg:
 		log.Debugf("Stop Running Task Message")
-		taskEnd := time.Now()
-		m.runningTask.End = &taskEnd
+		m.runningTask.Stop(time.Now())
 		m.task, cmd = m.task.Update(msg)
 		return m, tea.Batch(cmd, func() tea.Msg {
 			return AddEntryMsg{
 				Entry: m.runningTask,
 			}
 		})
-	case AddEntryMsg:
-		log.Debugf("Add Entry Message: %v", msg)
-		err := dbaccess.AddEntry(m.db, m.runningTask)
+	case task.PauseRunningTaskMsg:
+		if m.runningTask == nil {
+			return m, nil
+		}
+		var err error
+		if m.runningTask.Paused() {
+			err = m.runningTask.Resume(time.Now())
+		} else {
+			err = m.runningTask.Pause(time.Now())
+		}
 		if err != nil {
-			log.Errorf("Error adding entry: %v", err)
+			return m, notify.Errorf("%v", err)
 		}
+		saved := m.save(m.runningTask)
+		m.task, cmd = m.task.Update(msg)
+		return m, tea.Batch(cmd, saved)
+	case AddEntryMsg:
+		log.Debugf("Add Entry Message: %v", msg)
+		// the running entry was inserted on start, it only needs its end persisted
+		saved := m.save(msg.Entry)
 		m.entryList, _ = m.entryList.Update(l.AddEntryMsg{Entry: msg.Entry})
-		return m, func() tea.Msg {
+		m.report, _ = m.report.Update(report.AddEntryMsg{Entry: msg.Entry})
+		return m, tea.Batch(saved, func() tea.Msg {
 			return EntryAddedMsg{}
-		}
+		})
 	case tea.WindowSizeMsg:
 		log.Debugf("Window Size Changed")
 		m.width, m.height = msg.Width, msg.Height
 		m.task, _ = m.task.Update(msg)
+		m.report, _ = m.report.Update(msg)
 	case list.FilterMatchesMsg:
 		log.Debugf("Filter Matches Message")
 		m.entryList, _ = m.entryList.Update(msg)
 	case editor.EntryEditedMsg:
-		if msg.Entry != m.runningTask {
-			log.Debugf("replacing entry: %v", msg.Entry)
-			m.dirtyTask = msg.Entry
-		}
+		log.Debugf("replacing entry: %v", msg.Entry)
+		m.dirtyTask, m.dirtyEdit = msg.Entry, msg.Edit
 	case l.EntryChangedMsg:
 		log.Debugf("Select Entry Message")
-		m.saveChanges()
+		cmd = m.saveChanges()
 		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: msg.SelectedEntry})
-		return m, nil
+		return m, cmd
 	case l.EntrySelectedMsg:
 		log.Debugf("Edit Entry Message")
-		m.saveChanges()
+		cmd = m.saveChanges()
 		m.focused = Editor
+	case l.DeleteEntryMsg:
+		log.Debugf("Delete Entry Message: %v", msg.Entry)
+		if m.dirtyTask == msg.Entry {
+			m.dirtyTask = nil
+		}
+		err := m.db.DeleteEntry(msg.Entry)
+		if err != nil {
+			log.Errorf("could not delete entry %s: %v", msg.Entry.ObjectId, err)
+			retry := msg
+			cmd = notify.Retryable(func() tea.Msg { return retry }, "could not delete %s: %v", msg.Entry.Name, err)
+		}
+		m.report, _ = m.report.Update(report.RemoveEntryMsg{Entry: msg.Entry})
+		if m.editor.Entry() == msg.Entry {
+			m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: m.runningTask})
+		}
+	case l.RestoreEntryMsg:
+		log.Debugf("Restore Entry Message: %v", msg.Entry)
+		// the deleted document is gone, the entry is stored as a new one
+		msg.Entry.ObjectId = ""
+		cmd = m.save(msg.Entry)
+		m.report, _ = m.report.Update(report.AddEntryMsg{Entry: msg.Entry})
+	case l.DuplicateEntryMsg:
+		log.Debugf("Duplicate Entry Message: %v", msg.Entry)
+		cmd = tea.Batch(m.saveChanges(), m.save(msg.Entry))
+		m.report, _ = m.report.Update(report.AddEntryMsg{Entry: msg.Entry})
+		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: msg.Entry})
+		m.focused = Editor
+	case l.NewEntryMsg:
+		saved := m.saveChanges()
+		m.add, cmd = m.add.Update(add.OpenMsg{})
+		m.focused = Add
+		return m, tea.Batch(cmd, saved)
+	case add.EntryCreatedMsg:
+		log.Debugf("Entry Created Message: %v", msg.Entry)
+		saved := m.save(msg.Entry)
+		m.entryList, cmd = m.entryList.Update(l.AddEntryMsg{Entry: msg.Entry})
+		m.report, _ = m.report.Update(report.AddEntryMsg{Entry: msg.Entry})
+		m.focused = EntryList
+		return m, tea.Batch(cmd, saved, loadProjects(m.db))
+	case add.CancelledMsg:
+		m.focused = EntryList
+	case l.ContinueEntryMsg:
+		if m.runningTask != nil {
+			log.Infof("not continuing %s, %s is still running", msg.Entry.Name, m.runningTask.Name)
+			return m, notify.Warnf("%s is still running, stop it first", m.runningTask.Name)
+		}
+		return m, tea.Batch(m.saveChanges(), func() tea.Msg {
+			return task.StartRunningMsg{RunningTask: msg.Entry.ContinueAt(time.Now())}
+		})
+	case task.AdjustStartMsg:
+		return m, m.adjustStart(msg)
+	case task.TrimNeededMsg:
+		m.task, cmd = m.task.Update(msg)
+	case task.SwitchTaskMsg:
+		return m, m.switchTo(msg.Next)
+	case l.SwitchToEntryMsg:
+		return m, m.switchTo(msg.Entry.ContinueAt(time.Time{}))
 	case task.EditRunningTaskMsg:
-		m.saveChanges()
+		cmd = m.saveChanges()
 		if m.runningTask != nil {
-			m.editor, cmd = m.editor.Update(editor.EntryListSelectedMsg{Entry: m.runningTask})
+			m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: m.runningTask})
 		}
 		m.focused = Editor
-	case spinner.TickMsg:
+	case spinner.TickMsg, task.PhaseTickMsg:
 		m.task, cmd = m.task.Update(msg)
+	case themes.ChangedMsg:
+		log.Infof("Switching to theme %s", msg.Name)
+		m.theme, m.themeName = msg.Theme, msg.Name
+		m.task, _ = m.task.Update(msg)
+		m.entryList, _ = m.entryList.Update(msg)
+		m.editor, _ = m.editor.Update(msg)
+		m.add, _ = m.add.Update(msg)
+		m.report, _ = m.report.Update(msg)
+		m.away, _ = m.away.Update(msg)
+		m.notify, _ = m.notify.Update(msg)
+	case notify.Msg:
+		m.notify, cmd = m.notify.Update(msg)
+	case saveChangesMsg:
+		if m.dirtyTask == nil {
+			break
+		}
+		name := m.dirtyEdit.Name
+		cmd = m.saveChanges()
+		if m.dirtyTask == nil {
+			cmd = tea.Batch(cmd, notify.Infof("saved %s", name))
+		}
+	case saveEntryMsg:
+		cmd = m.save(msg.Entry)
+		if cmd == nil {
+			cmd = notify.Infof("saved %s", msg.Entry.Name)
+		}
+	case switchEntryMsg:
+		if msg.Next.ObjectId != "" {
+			// next was saved on its own meanwhile, e.g. when it was paused
+			cmd = m.save(msg.Ended)
+		} else {
+			cmd = m.switchEntry(msg.Ended, msg.Next)
+		}
+		if cmd == nil {
+			cmd = notify.Infof("switched to %s", msg.Next.Name)
+		}
+	case report.ExportMsg:
+		return m, exportEntries(m.db, msg)
+	case backupMsg:
+		return m, tea.Batch(runBackup(m.db, m.backup), scheduleBackup())
+	case idleCheckMsg:
+		if m.runningTask == nil || m.showAway {
+			return m, scheduleIdleCheck()
+		}
+		if m.idleSource != nil {
+			return m, tea.Batch(querySystemIdle(m.idleSource), scheduleIdleCheck())
+		}
+		m.checkIdle(time.Since(m.lastActivity), time.Now())
+		return m, scheduleIdleCheck()
+	case systemIdleMsg:
+		if msg.err != nil {
+			// keys in the TUI are all there is from now on
+			log.Errorf("%v", msg.err)
+			m.idleSource = nil
+			return m, notify.Warnf("idle command failed, only keys in timekeeper count as activity: %v", msg.err)
+		}
+		return m, m.checkIdle(msg.idle, time.Now())
+	case away.ResolvedMsg:
+		return m, m.resolveAway(msg)
+	case report.ExportedMsg:
+		if msg.Err != nil {
+			return m, notify.Errorf("export failed: %v", msg.Err)
+		}
+		return m, notify.Infof("exported %d entries to %s", msg.Count, msg.Path)
+	default:
+		// notifications time out on their own
+		m.notify, cmd = m.notify.Update(msg)
 	}
 	return m, cmd
 }
 
+// endPhase stops the running entry at the end of a Pomodoro interval and
+// starts the next one at the same moment.
+func (m *model) endPhase(msg task.PhaseEndedMsg) tea.Cmd {
+	if m.runningTask == nil {
+		return nil
+	}
+	return tea.Batch(notify.Infof("%s", msg.Text), m.replaceRunning(msg.At, msg.Next))
+}
+
+// replaceRunning ends the running entry at end and makes next the running
+// entry, a nil next leaves no task running. Both are stored in one store
+// operation, so they line up without a gap. The focus stays where it is.
+func (m *model) replaceRunning(end time.Time, next *models.Entry) tea.Cmd {
+	ended := m.runningTask
+	ended.Stop(end)
+	cmds := []tea.Cmd{m.saveChanges()}
+
+	var cmd tea.Cmd
+	m.runningTask = next
+	if next == nil {
+		cmds = append(cmds, m.storeFinished(ended))
+		m.task, cmd = m.task.Update(task.StopRunningTaskMsg{})
+		return tea.Batch(append(cmds, cmd)...)
+	}
+	cmds = append(cmds, m.switchEntry(ended, next))
+	m.entryList, _ = m.entryList.Update(l.AddEntryMsg{Entry: ended})
+	m.report, _ = m.report.Update(report.AddEntryMsg{Entry: ended})
+	if m.focused != Editor {
+		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: next})
+	}
+	m.report, _ = m.report.Update(report.AddEntryMsg{Entry: next})
+	m.task, cmd = m.task.Update(task.StartRunningMsg{RunningTask: next})
+	return tea.Batch(append(cmds, cmd)...)
+}
+
+// storeFinished saves a finished entry and adds it to the list and the report.
+func (m *model) storeFinished(e *models.Entry) tea.Cmd {
+	saved := m.save(e)
+	m.entryList, _ = m.entryList.Update(l.AddEntryMsg{Entry: e})
+	m.report, _ = m.report.Update(report.AddEntryMsg{Entry: e})
+	return saved
+}
+
+// switchEntry stores the end of ended and the new running entry next. A
+// failure leaves the store unchanged and can be retried.
+func (m *model) switchEntry(ended, next *models.Entry) tea.Cmd {
+	err := m.db.SwitchEntry(ended, next)
+	if err == nil {
+		return nil
+	}
+	log.Errorf("could not switch from %q to %q: %v", ended.Name, next.Name, err)
+	return notify.Retryable(func() tea.Msg {
+		return switchEntryMsg{Ended: ended, Next: next}
+	}, "could not switch to %s: %v", next.Name, err)
+}
+
+// switchTo stops the running task and starts next at the same moment. Without
+// a running task next is just started.
+func (m *model) switchTo(next *models.Entry) tea.Cmd {
+	now := time.Now()
+	next.Start = now
+	if m.runningTask == nil {
+		return tea.Batch(m.saveChanges(), func() tea.Msg {
+			return task.StartRunningMsg{RunningTask: next}
+		})
+	}
+	log.Infof("switching from %s to %s", m.runningTask.Name, next.Name)
+	// the entry might have introduced a new project
+	return tea.Batch(m.replaceRunning(now, next), loadProjects(m.db))
+}
+
+// adjustStart moves the start of the running task. A start before the end of
+// the previous entry needs msg.Trim, which cuts that entry short.
+func (m *model) adjustStart(msg task.AdjustStartMsg) tea.Cmd {
+	running := m.runningTask
+	if running == nil {
+		return nil
+	}
+	if err := models.ValidateTimes(msg.Start, nil, time.Now()); err != nil {
+		return notify.Errorf("start: %v", err)
+	}
+	previous, err := m.overlap(running, msg.Start, nil)
+	if err != nil {
+		return notify.Errorf("start: %v", err)
+	}
+	var cmds []tea.Cmd
+	if previous != nil {
+		if !msg.Trim {
+			msg.Trim = true
+			return func() tea.Msg {
+				return task.TrimNeededMsg{Start: msg.Start, Previous: previous, Confirmed: msg}
+			}
+		}
+		cmds = append(cmds, m.trimPrevious(previous, running, msg.Start))
+	}
+	from := running.Start
+	running.Start = msg.Start
+	cmds = append(cmds, m.save(running))
+	if m.editor.Entry() == running {
+		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: running})
+	}
+	var cmd tea.Cmd
+	m.task, cmd = m.task.Update(task.StartAdjustedMsg{From: from})
+	return tea.Batch(append(cmds, cmd)...)
+}
+
+// overlap finds the listed entry e would overlap from start to end, see
+// models.Overlap, and also refuses to overlap the running task.
+func (m *model) overlap(e *models.Entry, start time.Time, end *time.Time) (*models.Entry, error) {
+	now := time.Now()
+	until := now
+	if end != nil {
+		until = *end
+	}
+	previous, err := models.Overlap(m.entryList.Entries(), e, start, end, now)
+	if err != nil {
+		return nil, err
+	}
+	if r := m.runningTask; r != nil && r != e && r.Start.Before(until) {
+		return nil, fmt.Errorf("it overlaps the running %s", r.Name)
+	}
+	return previous, nil
+}
+
+// trimPrevious cuts previous short, so e can start at start.
+func (m *model) trimPrevious(previous, e *models.Entry, start time.Time) tea.Cmd {
+	log.Infof("cutting %s short to start %s at %s", previous.Name, e.Name, start.Format(time.RFC3339))
+	previous.End = &start
+	cmd := m.save(previous)
+	if m.editor.Entry() == previous {
+		// the editor shows the trimmed entry, what was typed into it is dropped
+		if m.dirtyTask == previous {
+			m.dirtyTask = nil
+		}
+		m.editor, _ = m.editor.Update(editor.EntryListSelectedMsg{Entry: previous})
+	}
+	return cmd
+}
+
 // todo make async
+// saveChanges stores the changes made in the editor on a copy of the entry and
+// applies them to the entry shown everywhere else only once they are stored.
+// If that fails they stay dirty, so leaving the editor again or the retry of
+// the notification stores them later.
 func (m *model) saveChanges() tea.Cmd {
-	// Todo error handling, messages, everything
-	if m.dirtyTask != nil {
-		log.Debugf("Saving changes to database")
-		dbaccess.UpdateEntry(m.db, m.dirtyTask)
-		m.dirtyTask = nil
+	if m.dirtyTask == nil {
+		return nil
 	}
-	return nil
+	log.Debugf("Saving changes to database")
+	e := m.dirtyTask
+	updated := m.dirtyEdit.Apply(e)
+	var err error
+	if updated.ObjectId == "" {
+		err = m.db.AddEntry(updated)
+	} else {
+		err = m.db.UpdateEntry(updated)
+	}
+	if err != nil {
+		log.Errorf("could not save entry %q: %v", updated.Name, err)
+		return notify.Retryable(func() tea.Msg {
+			return saveChangesMsg{}
+		}, "could not save %s: %v", updated.Name, err)
+	}
+	m.dirtyTask = nil
+	moved := !updated.Start.Equal(e.Start)
+	*e = *updated
+	if !moved {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.entryList, cmd = m.entryList.Update(l.EntryMovedMsg{Entry: e})
+	return cmd
+}
+
+// save inserts an entry without id and updates all others. A failure is
+// returned as a notification that retries the save.
+func (m *model) save(e *models.Entry) tea.Cmd {
+	var err error
+	if e.ObjectId == "" {
+		err = m.db.AddEntry(e)
+	} else {
+		err = m.db.UpdateEntry(e)
+	}
+	if err == nil {
+		return nil
+	}
+	log.Errorf("could not save entry %q: %v", e.Name, err)
+	return notify.Retryable(func() tea.Msg {
+		return saveEntryMsg{Entry: e}
+	}, "could not save %s: %v", e.Name, err)
 }
 
 func (m model) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	key := msg.String()
-	switch key {
-	case "ctrl+c":
-		m.saveChanges()
+	now := time.Now()
+	if m.idleSource == nil && m.runningTask != nil && m.idleThreshold > 0 && now.Sub(m.lastActivity) >= m.idleThreshold {
+		m.checkIdle(now.Sub(m.lastActivity), now)
+	}
+	m.lastActivity = now
+	if !m.awaySince.IsZero() && !m.showAway {
+		// the key only brings the user back
+		return m, m.openAway(now)
+	}
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		if cmd := m.saveChanges(); m.dirtyTask != nil && !m.quitting {
+			m.quitting = true
+			return m, tea.Batch(cmd, notify.Warnf("unsaved changes, press %s again to quit anyway", m.keys.Quit.Help().Key))
+		}
 		return m, tea.Quit
-	case "tab":
+	case m.showAway:
+		var cmd tea.Cmd
+		m.away, cmd = m.away.Update(msg)
+		return m, cmd
+	case key.Matches(msg, m.keys.Retry):
+		var cmd tea.Cmd
+		m.notify, cmd = m.notify.Retry()
+		return m, cmd
+	case key.Matches(msg, m.keys.Dismiss):
+		m.notify = m.notify.Dismiss()
+		return m, nil
+	case key.Matches(msg, m.keys.History):
+		m.showHistory = !m.showHistory
+		return m, nil
+	case m.showHistory:
+		if key.Matches(msg, m.keys.Cancel) {
+			m.showHistory = false
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Theme):
+		name := themes.Next(m.themeName)
+		theme, _ := themes.ByName(name)
+		return m, func() tea.Msg {
+			return themes.ChangedMsg{Name: name, Theme: theme}
+		}
+	case m.showHelp:
+		// the overlay swallows everything but quit and theme switching
+		if key.Matches(msg, m.keys.Help, m.keys.Cancel) {
+			m.showHelp = false
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Help) && !m.typing():
+		m.showHelp = true
+		return m, nil
+	case key.Matches(msg, m.keys.NextFocus):
 		return m, func() tea.Msg {
 			return NextFocusMsg{}
 		}
@@ -206,20 +720,54 @@ func (m model) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		el, cmd := m.entryList.Update(msg)
 		m.entryList = el
 		return m, cmd
+	case Report:
+		var cmd tea.Cmd
+		m.report, cmd = m.report.Update(msg)
+		return m, cmd
+	case Add:
+		var cmd tea.Cmd
+		m.add, cmd = m.add.Update(msg)
+		return m, cmd
 	default:
 		log.Debugf("no handle for focus: %v", m.focused)
 		return m, nil
 	}
 }
 
+// typing reports whether the focused component takes text, its keys are not global then.
+func (m model) typing() bool {
+	switch m.focused {
+	case Task:
+		return m.task.Typing()
+	case EntryList:
+		return m.entryList.Typing()
+	case Editor, Add:
+		return true
+	}
+	return false
+}
+
 func (m model) View() string {
+	if m.showHelp {
+		return m.helpView()
+	}
+	if m.showAway {
+		status := m.theme.SubtextStyle().PaddingLeft(1).Render("Timekeeper \uF444 " + m.away.StatusBar())
+		dialog := lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, m.theme.ActiveWidgetStyle().Render(m.away.View()))
+		return lipgloss.JoinVertical(lipgloss.Left, dialog, status)
+	}
+	if m.showHistory {
+		status := m.theme.SubtextStyle().PaddingLeft(1).Render("Timekeeper \uF444 " + keys.Status(m.keys.History, m.keys.Cancel))
+		return lipgloss.JoinVertical(lipgloss.Left, m.theme.WidgetStyle().Width(m.width-2).Render(m.notify.HistoryView(m.height-4)), status)
+	}
 	leftWidth := (m.width / 2) - 2
 	rightWidth := leftWidth
 
-	var t, li, e string
+	var t, li, e, r string
 	t = m.theme.WidgetStyle().Width(leftWidth).Render(m.task.View())
 	li = m.theme.WidgetStyle().Width(leftWidth).Render(m.entryList.View())
 	e = m.theme.WidgetStyle().Width(leftWidth).Render(m.editor.View())
+	r = m.theme.WidgetStyle().Width(rightWidth).Render(m.report.View())
 
 	switch m.focused {
 	case Task:
@@ -228,12 +776,19 @@ func (m model) View() string {
 		li = m.theme.ActiveWidgetStyle().Width(leftWidth).Render(m.entryList.View())
 	case Editor:
 		e = m.theme.ActiveWidgetStyle().Width(rightWidth).Render(m.editor.View())
+	case Report:
+		r = m.theme.ActiveWidgetStyle().Width(rightWidth).Render(m.report.View())
+	case Add:
+		e = m.theme.ActiveWidgetStyle().Width(rightWidth).Render(m.add.View())
 	}
 
 	s := lipgloss.JoinHorizontal(lipgloss.Left,
 		lipgloss.JoinVertical(lipgloss.Top, t, li),
-		e)
+		lipgloss.JoinVertical(lipgloss.Top, e, r))
 	status := "Timekeeper \uF444 "
+	if n := m.task.Pomodoros(time.Now()); n > 0 || m.task.PomodoroOn() {
+		status += fmt.Sprintf("%d pomodoros today \uF444 ", n)
+	}
 	switch m.focused {
 	case Task:
 		status = status + m.task.StatusBar()
@@ -241,22 +796,230 @@ func (m model) View() string {
 		status = status + m.entryList.StatusBar()
 	case Editor:
 		status = status + m.editor.StatusBar()
+	case Report:
+		status = status + m.report.StatusBar()
+	case Add:
+		status = status + m.add.StatusBar()
+	}
+	status = status + " \uF444 " + keys.Status(m.keys.NextFocus, m.keys.Help)
+	status = m.theme.SubtextStyle().Render(status)
+	if m.notify.Active() {
+		status = m.notify.View()
 	}
 	s = lipgloss.JoinVertical(
-		lipgloss.Left, s, m.theme.SubtextStyle().PaddingLeft(1).Render(status))
+		lipgloss.Left, s, lipgloss.NewStyle().PaddingLeft(1).Render(status))
 	return s
 }
 
-func Run(db *clover.DB) error {
-	p := tea.NewProgram(initialModel(db), tea.WithAltScreen())
+// helpView lists all bindings grouped by pane.
+func (m model) helpView() string {
+	columns := make([]string, 0)
+	for _, g := range m.keys.Groups() {
+		lines := []string{m.theme.AccentStyle().Render(g.Title)}
+		for _, b := range g.Bindings {
+			if !b.Enabled() {
+				continue
+			}
+			lines = append(lines, m.theme.NormalStyle().Render(fmt.Sprintf("%-12s", b.Help().Key))+" "+m.theme.SubtextStyle().Render(b.Help().Desc))
+		}
+		columns = append(columns, m.theme.WidgetStyle().Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+	}
+	// wrap the groups into rows that fit the window
+	var rows, row []string
+	for _, c := range columns {
+		if len(row) > 0 && lipgloss.Width(lipgloss.JoinHorizontal(lipgloss.Top, append(row, c)...)) > m.width {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row = nil
+		}
+		row = append(row, c)
+	}
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	help := lipgloss.JoinVertical(lipgloss.Left, rows...)
+	status := m.theme.SubtextStyle().PaddingLeft(1).Render("Timekeeper \uF444 theme " + m.themeName + " \uF444 " + keys.Status(m.keys.Help, m.keys.Cancel, m.keys.Theme, m.keys.Quit))
+	return lipgloss.JoinVertical(lipgloss.Left, help, status)
+}
+
+// Run starts the TUI, warnings are shown as notifications right away.
+func Run(db dbaccess.EntryStore, cfg config.Config, warnings ...error) error {
+	m := initialModel(db, cfg)
+	m.startup = warnings
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
 }
 
-func loadEntries(db *clover.DB) tea.Cmd {
+func loadEntries(db dbaccess.EntryStore) tea.Cmd {
 	log.Infof("Loading entries...")
 	return func() tea.Msg {
-		loadedEntries := dbaccess.LoadEntries(db)
-		return l.EntriesLoadedMsg{Entries: loadedEntries}
+		loadedEntries, err := db.LoadEntries()
+		if err != nil && loadedEntries == nil {
+			log.Errorf("could not load entries: %v", err)
+			return notify.Retryable(loadEntries(db), "could not load entries: %v", err)()
+		}
+		// the running entry lives in the task widget until it is stopped
+		finishedEntries := make([]*models.Entry, 0, len(loadedEntries))
+		for _, e := range loadedEntries {
+			if e.End != nil {
+				finishedEntries = append(finishedEntries, e)
+			}
+		}
+		loaded := func() tea.Msg {
+			return l.EntriesLoadedMsg{Entries: finishedEntries}
+		}
+		if err != nil {
+			// some entries were unreadable, show the rest and the error
+			log.Warnf("%v", err)
+			return tea.BatchMsg{loaded, notify.Warnf("%v", err)}
+		}
+		return loaded()
+	}
+}
+
+func scheduleIdleCheck() tea.Cmd {
+	return tea.Tick(idleCheckInterval, func(time.Time) tea.Msg {
+		return idleCheckMsg{}
+	})
+}
+
+func querySystemIdle(source idle.Source) tea.Cmd {
+	return func() tea.Msg {
+		d, err := source.Idle()
+		return systemIdleMsg{idle: d, err: err}
+	}
+}
+
+// checkIdle notes when the running task went idle and opens the away dialog
+// once the idle source shows activity again.
+func (m *model) checkIdle(idle time.Duration, now time.Time) tea.Cmd {
+	if m.runningTask == nil || m.idleThreshold <= 0 {
+		return nil
+	}
+	// paused time isn't tracked, there is nothing to ask about
+	if m.runningTask.Paused() {
+		m.awaySince = time.Time{}
+		return nil
+	}
+	if m.awaySince.IsZero() {
+		if idle >= m.idleThreshold {
+			m.awaySince = now.Add(-idle)
+			if m.awaySince.Before(m.runningTask.Start) {
+				m.awaySince = m.runningTask.Start
+			}
+			log.Infof("idle since %s", m.awaySince.Format(time.RFC3339))
+		}
+		return nil
+	}
+	if idle < m.idleThreshold && !m.showAway {
+		return m.openAway(now.Add(-idle))
+	}
+	return nil
+}
+
+func (m *model) openAway(returned time.Time) tea.Cmd {
+	if m.runningTask == nil {
+		m.awaySince = time.Time{}
+		return nil
+	}
+	m.showAway = true
+	m.away, _ = m.away.Update(away.OpenMsg{Since: m.awaySince, Returned: returned})
+	return nil
+}
+
+// resolveAway applies the choice of the away dialog. Discarding and
+// reassigning end the running entry where the idle time began and continue
+// the task as a new entry from the moment the user returned.
+func (m *model) resolveAway(msg away.ResolvedMsg) tea.Cmd {
+	m.showAway = false
+	m.awaySince = time.Time{}
+	running := m.runningTask
+	if running == nil {
+		return nil
+	}
+	// a Pomodoro interval might have started a new entry while the dialog was open
+	if msg.Since.Before(running.Start) {
+		msg.Since = running.Start
+	}
+	if !msg.Returned.After(msg.Since) {
+		return nil
+	}
+	switch msg.Choice {
+	case away.Discard:
+		log.Infof("discarding idle time %s to %s of %s", msg.Since.Format(time.RFC3339), msg.Returned.Format(time.RFC3339), running.Name)
+		return m.replaceRunning(msg.Since, running.ContinueAt(msg.Returned))
+	case away.Reassign:
+		log.Infof("reassigning idle time %s to %s of %s to %s", msg.Since.Format(time.RFC3339), msg.Returned.Format(time.RFC3339), running.Name, msg.Entry.Name)
+		cmd := m.replaceRunning(msg.Since, running.ContinueAt(msg.Returned))
+		msg.Entry.Start = msg.Since
+		return tea.Batch(cmd, m.storeFinished(msg.Entry), loadProjects(m.db))
+	}
+	return nil
+}
+
+// scheduleBackup looks for a due backup every hour, so sessions left open for days are backed up too.
+func scheduleBackup() tea.Cmd {
+	return tea.Tick(time.Hour, func(time.Time) tea.Msg {
+		return backupMsg{}
+	})
+}
+
+func runBackup(db dbaccess.EntryStore, o backup.Options) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := backup.Scheduled(db, o, time.Now()); err != nil {
+			log.Errorf("backup failed: %v", err)
+			return notify.Retryable(runBackup(db, o), "backup failed: %v", err)()
+		}
+		return nil
+	}
+}
+
+func loadProjects(db dbaccess.EntryStore) tea.Cmd {
+	return func() tea.Msg {
+		projects, err := db.LoadProjects()
+		if err != nil {
+			log.Warnf("could not load projects: %v", err)
+			return nil
+		}
+		return task.ProjectsLoadedMsg{Projects: projects}
+	}
+}
+
+func loadRunning(db dbaccess.EntryStore) tea.Cmd {
+	return func() tea.Msg {
+		running, err := db.GetRunning()
+		if err != nil {
+			log.Warnf("could not restore running entry: %v", err)
+			return notify.Msg{Severity: notify.Error, Text: fmt.Sprintf("could not restore the running task: %v", err)}
+		}
+		if running == nil {
+			return nil
+		}
+		log.Infof("Restoring running entry %s", running.ObjectId)
+		return task.StartRunningMsg{RunningTask: running}
+	}
+}
+
+func exportEntries(db dbaccess.EntryStore, msg report.ExportMsg) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			dir = "."
+		}
+		f, _ := export.Lookup(msg.Format)
+		name := fmt.Sprintf("timekeeper-%s-%s.%s", msg.From.Format("20060102"), msg.To.AddDate(0, 0, -1).Format("20060102"), f.Extension())
+		opts := export.Options{
+			From:   msg.From,
+			To:     msg.To,
+			Format: msg.Format,
+			Path:   filepath.Join(dir, name),
+		}
+		entries, err := db.LoadEntriesBetween(opts.From, opts.To)
+		if err != nil {
+			return report.ExportedMsg{Err: err}
+		}
+		n, err := export.Export(entries, opts)
+		if err != nil {
+			log.Warnf("export failed: %v", err)
+		}
+		return report.ExportedMsg{Path: opts.Path, Count: n, Err: err}
 	}
 }
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/danielroehrig/timekeeper/app/ui/notify"
+	"github.com/danielroehrig/timekeeper/config"
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+)
+
+// probe stops the program once the notification it waits for arrives.
+type probe struct {
+	tea.Model
+	text string
+	seen chan<- struct{}
+}
+
+func (p probe) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if n, ok := msg.(notify.Msg); ok && n.Text == p.text {
+		close(p.seen)
+		return p, tea.Quit
+	}
+	m, cmd := p.Model.Update(msg)
+	return probe{Model: m, text: p.text, seen: p.seen}, cmd
+}
+
+func TestInitShowsStartupWarningsRightAway(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TIMEKEEPER_CONFIG", filepath.Join(dir, "config.yml"))
+	t.Setenv("TIMEKEEPER_DATABASE_PATH", dir)
+	t.Setenv("TIMEKEEPER_LOG_FILE", filepath.Join(dir, "log"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BackupOptions().Interval == 0 || cfg.IdleThreshold == 0 {
+		t.Fatal("backups and idle detection are expected to be on by default")
+	}
+	db, err := dbaccess.Open(dbaccess.SQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := initialModel(db, cfg)
+	m.startup = []error{errors.New("migration failed")}
+	seen := make(chan struct{})
+	p := tea.NewProgram(probe{Model: m, text: "migration failed", seen: seen},
+		tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer())
+	go func() {
+		select {
+		case <-seen:
+		case <-time.After(5 * time.Second):
+			p.Kill()
+		}
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatalf("the startup warning did not arrive: %v", err)
+	}
+}
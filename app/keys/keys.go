@@ -0,0 +1,220 @@
+package keys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds every binding of the TUI. Components only match keys against it
+// so the status bar and the help overlay always show what is actually bound.
+type KeyMap struct {
+	// global
+	Quit      key.Binding
+	NextFocus key.Binding
+	Help      key.Binding
+	Theme     key.Binding
+	Retry     key.Binding
+	Dismiss   key.Binding
+	History   key.Binding
+
+	// task
+	Start       key.Binding
+	Stop        key.Binding
+	Pause       key.Binding
+	Switch      key.Binding
+	AdjustStart key.Binding
+	EditRunning key.Binding
+	ProjectPrev key.Binding
+	ProjectNext key.Binding
+	Pomodoro    key.Binding
+
+	// entry list
+	Edit      key.Binding
+	Add       key.Binding
+	Delete    key.Binding
+	Confirm   key.Binding
+	Undo      key.Binding
+	Duplicate key.Binding
+	Continue  key.Binding
+	SwitchTo  key.Binding
+	Filter    key.Binding
+
+	// forms, the editor and the add entry form
+	NextField key.Binding
+	PrevField key.Binding
+	FormDown  key.Binding
+	FormUp    key.Binding
+	Accept    key.Binding
+	Save      key.Binding
+	Cancel    key.Binding
+
+	// report
+	PrevPeriod   key.Binding
+	NextPeriod   key.Binding
+	Day          key.Binding
+	Week         key.Binding
+	Month        key.Binding
+	GroupBy      key.Binding
+	Export       key.Binding
+	ExportFormat key.Binding
+
+	// away dialog
+	AwayKeep     key.Binding
+	AwayDiscard  key.Binding
+	AwayReassign key.Binding
+}
+
+type Group struct {
+	Title    string
+	Bindings []*key.Binding
+}
+
+func Default() KeyMap {
+	return KeyMap{
+		Quit:      binding("quit", "ctrl+c"),
+		NextFocus: binding("next pane", "tab"),
+		Help:      binding("help", "?"),
+		Theme:     binding("next theme", "ctrl+t"),
+		Retry:     binding("retry", "ctrl+r"),
+		Dismiss:   binding("dismiss", "ctrl+x"),
+		History:   binding("notifications", "ctrl+l"),
+
+		Start:       binding("start", "enter"),
+		Stop:        binding("stop", " "),
+		Pause:       binding("pause", "p"),
+		Switch:      binding("switch to", "s"),
+		AdjustStart: binding("adjust start", "t"),
+		EditRunning: binding("edit", "enter"),
+		ProjectPrev: binding("previous project", "up"),
+		ProjectNext: binding("next project", "down"),
+		Pomodoro:    binding("pomodoro", "ctrl+o"),
+
+		Edit:      binding("edit", "enter"),
+		Add:       binding("add", "a"),
+		Delete:    binding("delete", "x", "delete"),
+		Confirm:   binding("confirm", "y"),
+		Undo:      binding("undo delete", "z"),
+		Duplicate: binding("duplicate", "c"),
+		Continue:  binding("continue", "r"),
+		SwitchTo:  binding("switch to", "s"),
+		Filter:    binding("filter", "/"),
+
+		NextField: binding("next field", "ctrl+n"),
+		PrevField: binding("previous field", "ctrl+p"),
+		FormDown:  binding("field below", "down"),
+		FormUp:    binding("field above", "up"),
+		Accept:    binding("next field", "enter"),
+		Save:      binding("save", "ctrl+s"),
+		Cancel:    binding("cancel", "esc"),
+
+		PrevPeriod:   binding("previous period", "left", "h"),
+		NextPeriod:   binding("next period", "right", "l"),
+		Day:          binding("day", "d"),
+		Week:         binding("week", "w"),
+		Month:        binding("month", "m"),
+		GroupBy:      binding("group by", "g"),
+		Export:       binding("export", "e"),
+		ExportFormat: binding("export format", "f"),
+
+		AwayKeep:     binding("keep", "k"),
+		AwayDiscard:  binding("discard", "d"),
+		AwayReassign: binding("reassign", "r"),
+	}
+}
+
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKey(keys), desc))
+}
+
+func helpKey(keys []string) string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k == " " {
+			k = "space"
+		}
+		names = append(names, k)
+	}
+	return strings.Join(names, "/")
+}
+
+// Groups lists the bindings by the pane they belong to, in the order of the help overlay.
+func (k *KeyMap) Groups() []Group {
+	return []Group{
+		{Title: "Global", Bindings: []*key.Binding{&k.Quit, &k.NextFocus, &k.Help, &k.Theme, &k.Retry, &k.Dismiss, &k.History}},
+		{Title: "Task", Bindings: []*key.Binding{&k.Start, &k.Stop, &k.Pause, &k.Switch, &k.AdjustStart, &k.EditRunning, &k.ProjectPrev, &k.ProjectNext, &k.Pomodoro}},
+		{Title: "Entries", Bindings: []*key.Binding{&k.Edit, &k.Add, &k.Delete, &k.Confirm, &k.Undo, &k.Duplicate, &k.Continue, &k.SwitchTo, &k.Filter}},
+		{Title: "Forms", Bindings: []*key.Binding{&k.NextField, &k.PrevField, &k.FormDown, &k.FormUp, &k.Accept, &k.Save, &k.Cancel}},
+		{Title: "Report", Bindings: []*key.Binding{&k.PrevPeriod, &k.NextPeriod, &k.Day, &k.Week, &k.Month, &k.GroupBy, &k.Export, &k.ExportFormat}},
+		{Title: "Away", Bindings: []*key.Binding{&k.AwayKeep, &k.AwayDiscard, &k.AwayReassign}},
+	}
+}
+
+// names maps the config names of the bindings, e.g. "export_format", to the bindings.
+func (k *KeyMap) names() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit": &k.Quit, "next_focus": &k.NextFocus, "help": &k.Help, "theme": &k.Theme,
+		"retry": &k.Retry, "dismiss": &k.Dismiss, "history": &k.History,
+		"start": &k.Start, "stop": &k.Stop, "pause": &k.Pause, "switch": &k.Switch, "adjust_start": &k.AdjustStart, "edit_running": &k.EditRunning,
+		"project_prev": &k.ProjectPrev, "project_next": &k.ProjectNext, "pomodoro": &k.Pomodoro,
+		"edit": &k.Edit, "add": &k.Add, "delete": &k.Delete, "confirm": &k.Confirm, "undo": &k.Undo,
+		"duplicate": &k.Duplicate, "continue": &k.Continue, "switch_to": &k.SwitchTo, "filter": &k.Filter,
+		"next_field": &k.NextField, "prev_field": &k.PrevField, "form_down": &k.FormDown, "form_up": &k.FormUp,
+		"accept": &k.Accept, "save": &k.Save, "cancel": &k.Cancel,
+		"prev_period": &k.PrevPeriod, "next_period": &k.NextPeriod, "day": &k.Day, "week": &k.Week,
+		"month": &k.Month, "group_by": &k.GroupBy, "export": &k.Export, "export_format": &k.ExportFormat,
+		"away_keep": &k.AwayKeep, "away_discard": &k.AwayDiscard, "away_reassign": &k.AwayReassign,
+	}
+}
+
+// Names lists the config names of all bindings.
+func Names() []string {
+	k := Default()
+	names := make([]string, 0)
+	for name := range k.names() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Override replaces the keys of the named bindings, keeping their descriptions.
+// "space" can be used for the space bar, an empty list disables a binding.
+func (k *KeyMap) Override(bindings map[string][]string) error {
+	byName := k.names()
+	for name, keys := range bindings {
+		b, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown key binding %q, use one of %s", name, strings.Join(Names(), ", "))
+		}
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		normalized := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key == "space" {
+				key = " "
+			}
+			normalized = append(normalized, key)
+		}
+		b.SetKeys(normalized...)
+		b.SetHelp(helpKey(normalized), b.Help().Desc)
+		b.SetEnabled(true)
+	}
+	return nil
+}
+
+// Status renders bindings for the status bar like "<enter> start  <tab> next pane".
+func Status(bindings ...key.Binding) string {
+	parts := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("<%s> %s", b.Help().Key, b.Help().Desc))
+	}
+	return strings.Join(parts, " \uF444 ")
+}
@@ -0,0 +1,182 @@
+package add
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/models"
+	"github.com/danielroehrig/timekeeper/themes"
+	"github.com/danielroehrig/timekeeper/timeexpr"
+)
+
+// EntryCreatedMsg carries a finished entry that is not yet stored.
+type EntryCreatedMsg struct {
+	Entry *models.Entry
+}
+type CancelledMsg struct{}
+
+// OpenMsg resets the form before it gets the focus.
+type OpenMsg struct{}
+
+const (
+	nameField = iota
+	startField
+	endField
+	durationField
+	fieldCount
+)
+
+type Model struct {
+	inputs []textinput.Model
+	focus  int
+	err    error
+	theme  themes.Theme
+	layout models.Layout
+	parser timeexpr.Parser
+	keys   keys.KeyMap
+}
+
+func New(theme themes.Theme, layout models.Layout, parser timeexpr.Parser, km keys.KeyMap) Model {
+	placeholders := []string{"name @project #tag", layout.Time + ", yesterday 9am or 2h ago", layout.Time + ", empty with a duration", "1h30m or 1:30"}
+	inputs := make([]textinput.Model, fieldCount)
+	for i := range inputs {
+		inputs[i] = textinput.New()
+		inputs[i].Prompt = ""
+		inputs[i].Placeholder = placeholders[i]
+	}
+	m := Model{
+		inputs: inputs,
+		theme:  theme,
+		layout: layout,
+		parser: parser,
+		keys:   km,
+	}
+	return m.focusField(nameField)
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.handleKeypressAdd(msg)
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
+	case OpenMsg:
+		for i := range m.inputs {
+			m.inputs[i].Reset()
+		}
+		m.err = nil
+		return m.focusField(nameField), nil
+	}
+	return m, nil
+}
+
+func (m Model) handleKeypressAdd(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		return m, func() tea.Msg {
+			return CancelledMsg{}
+		}
+	case key.Matches(msg, m.keys.Save):
+		return m.submit()
+	case key.Matches(msg, m.keys.Accept):
+		if m.focus == fieldCount-1 {
+			return m.submit()
+		}
+		return m.focusField(m.focus + 1), nil
+	case key.Matches(msg, m.keys.FormDown, m.keys.NextField):
+		return m.focusField((m.focus + 1) % fieldCount), nil
+	case key.Matches(msg, m.keys.FormUp, m.keys.PrevField):
+		return m.focusField((m.focus + fieldCount - 1) % fieldCount), nil
+	}
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+func (m Model) submit() (Model, tea.Cmd) {
+	e, err := m.entry(time.Now())
+	m.err = err
+	if err != nil {
+		return m, nil
+	}
+	return m, func() tea.Msg {
+		return EntryCreatedMsg{Entry: e}
+	}
+}
+
+// entry builds the entry from the form. The end is either typed or start plus duration.
+func (m Model) entry(now time.Time) (*models.Entry, error) {
+	name, project, tags := models.ParseInput(m.inputs[nameField].Value())
+	if name == "" {
+		return nil, errors.New("name is missing")
+	}
+	start, err := m.parser.Time(m.inputs[startField].Value(), now, now)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	var end time.Time
+	switch {
+	case m.inputs[endField].Value() != "":
+		end, err = m.parser.End(m.inputs[endField].Value(), start, now)
+		if err != nil {
+			return nil, fmt.Errorf("end: %w", err)
+		}
+	case m.inputs[durationField].Value() != "":
+		d, err := m.parser.Duration(m.inputs[durationField].Value())
+		if err != nil {
+			return nil, fmt.Errorf("duration: %w", err)
+		}
+		end = start.Add(d)
+	default:
+		return nil, errors.New("either end or duration is needed")
+	}
+	if err := models.ValidateTimes(start, &end, now); err != nil {
+		return nil, err
+	}
+	return &models.Entry{
+		Name:    name,
+		Start:   start,
+		End:     &end,
+		Project: project,
+		Tags:    tags,
+	}, nil
+}
+
+func (m Model) focusField(f int) Model {
+	for i := range m.inputs {
+		m.inputs[i].Blur()
+	}
+	m.inputs[f].Focus()
+	m.focus = f
+	return m
+}
+
+func (m Model) View() string {
+	labels := []string{"name", "start", "end", "or for"}
+	lines := []string{m.theme.AccentStyle().Render("Add entry")}
+	for i, input := range m.inputs {
+		style := m.theme.SubtextStyle()
+		if i == m.focus {
+			style = m.theme.AccentStyle()
+		}
+		lines = append(lines, style.Render(fmt.Sprintf("%-6s", labels[i]))+" "+input.View())
+	}
+	if m.err != nil {
+		lines = append(lines, lipgloss.NewStyle().Foreground(m.theme.AltAccent()).Render(m.err.Error()))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m Model) StatusBar() string {
+	return keys.Status(m.keys.FormUp, m.keys.FormDown, m.keys.Save, m.keys.Cancel)
+}
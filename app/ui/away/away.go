@@ -0,0 +1,150 @@
+package away
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/models"
+	"github.com/danielroehrig/timekeeper/themes"
+)
+
+// Choice is what happens to the time the user was away.
+type Choice byte
+
+const (
+	Keep Choice = iota
+	Discard
+	Reassign
+)
+
+// OpenMsg shows the dialog for the time between Since and Returned.
+type OpenMsg struct {
+	Since    time.Time
+	Returned time.Time
+}
+
+// ResolvedMsg carries the user's choice. For Reassign, Entry is the away time
+// as a finished entry that is not yet stored.
+type ResolvedMsg struct {
+	Choice   Choice
+	Since    time.Time
+	Returned time.Time
+	Entry    *models.Entry
+}
+
+type Model struct {
+	since    time.Time
+	returned time.Time
+	reassign bool
+	input    textinput.Model
+	err      error
+	theme    themes.Theme
+	layout   models.Layout
+	keys     keys.KeyMap
+}
+
+func New(theme themes.Theme, layout models.Layout, km keys.KeyMap) Model {
+	i := textinput.New()
+	i.Prompt = " "
+	i.Placeholder = "what were you doing? @project #tag"
+	return Model{
+		input:  i,
+		theme:  theme,
+		layout: layout,
+		keys:   km,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.handleKeypressAway(msg)
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
+	case OpenMsg:
+		m.since, m.returned = msg.Since, msg.Returned
+		m.reassign = false
+		m.err = nil
+		m.input.Reset()
+		m.input.Blur()
+	}
+	return m, nil
+}
+
+func (m Model) handleKeypressAway(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if m.reassign {
+		switch {
+		case key.Matches(msg, m.keys.Cancel):
+			m.reassign = false
+			m.input.Blur()
+			return m, nil
+		case key.Matches(msg, m.keys.Accept):
+			name, project, tags := models.ParseInput(m.input.Value())
+			if name == "" {
+				m.err = errors.New("name is missing")
+				return m, nil
+			}
+			end := m.returned
+			return m, m.resolve(Reassign, &models.Entry{Name: name, Start: m.since, End: &end, Project: project, Tags: tags})
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+	switch {
+	case key.Matches(msg, m.keys.AwayKeep):
+		return m, m.resolve(Keep, nil)
+	case key.Matches(msg, m.keys.AwayDiscard):
+		return m, m.resolve(Discard, nil)
+	case key.Matches(msg, m.keys.AwayReassign):
+		m.reassign = true
+		return m, m.input.Focus()
+	}
+	return m, nil
+}
+
+func (m Model) resolve(c Choice, e *models.Entry) tea.Cmd {
+	msg := ResolvedMsg{Choice: c, Since: m.since, Returned: m.returned, Entry: e}
+	return func() tea.Msg {
+		return msg
+	}
+}
+
+func (m Model) View() string {
+	away := m.returned.Sub(m.since).Round(time.Minute)
+	lines := []string{
+		m.theme.AccentStyle().Render("You were away"),
+		fmt.Sprintf("from %s to %s, %s", m.since.Format(m.layout.Time), m.returned.Format(m.layout.Time), away),
+		"",
+	}
+	if m.reassign {
+		lines = append(lines, m.theme.SubtextStyle().Render("the away time becomes its own entry, the task goes on after it"), m.input.View())
+		if m.err != nil {
+			lines = append(lines, lipgloss.NewStyle().Foreground(m.theme.AltAccent()).Render(m.err.Error()))
+		}
+	} else {
+		lines = append(lines,
+			keys.Status(m.keys.AwayKeep)+" the time for the running task",
+			keys.Status(m.keys.AwayDiscard)+" the time, the task continues from now",
+			keys.Status(m.keys.AwayReassign)+" the time to another task",
+		)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m Model) StatusBar() string {
+	if m.reassign {
+		return keys.Status(m.keys.Accept, m.keys.Cancel)
+	}
+	return keys.Status(m.keys.AwayKeep, m.keys.AwayDiscard, m.keys.AwayReassign)
+}
@@ -1,32 +1,100 @@
 package editor
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
 	"github.com/danielroehrig/timekeeper/log"
 	"github.com/danielroehrig/timekeeper/models"
+	"github.com/danielroehrig/timekeeper/themes"
+	"github.com/danielroehrig/timekeeper/timeexpr"
 )
 
+// EntryEditedMsg carries the values typed for Entry. Entry itself stays as it
+// is until the edit is stored, see Edit.Apply.
 type EntryEditedMsg struct {
 	Entry *models.Entry
+	Edit  Edit
+}
+
+// Edit holds the fields of an entry the editor changes.
+type Edit struct {
+	Name    string
+	Start   time.Time
+	End     *time.Time
+	Content string
+}
+
+// Apply returns a copy of e with the edited fields. The end of an entry that
+// was running when the edit began is kept.
+func (ed Edit) Apply(e *models.Entry) *models.Entry {
+	c := *e
+	c.Name, c.Start, c.Content = ed.Name, ed.Start, ed.Content
+	if ed.End != nil {
+		c.End = ed.End
+	}
+	return &c
 }
+
 type EntryListSelectedMsg struct {
 	Entry *models.Entry
 }
 
+type field byte
+
+const (
+	nameField field = iota
+	startField
+	endField
+	contentField
+)
+
 type Model struct {
+	name    textinput.Model
+	start   textinput.Model
+	end     textinput.Model
 	content textarea.Model
+	focus   field
+	err     error
 	entry   *models.Entry
+	edit    Edit
+	theme   themes.Theme
+	layout  models.Layout
+	parser  timeexpr.Parser
+	keys    keys.KeyMap
 }
 
-func New() Model {
+func New(theme themes.Theme, layout models.Layout, parser timeexpr.Parser, km keys.KeyMap) Model {
 	t := textarea.New()
 	t.Focus()
 	return Model{
+		name:    newInput("name"),
+		start:   newInput(layout.DateTime()),
+		end:     newInput(layout.DateTime()),
 		content: t,
+		focus:   contentField,
+		theme:   theme,
+		layout:  layout,
+		parser:  parser,
+		keys:    km,
 	}
 }
 
+func newInput(placeholder string) textinput.Model {
+	i := textinput.New()
+	i.Prompt = ""
+	i.Placeholder = placeholder
+	return i
+}
+
 func (m Model) Init() tea.Cmd {
 	return nil
 }
@@ -34,32 +102,180 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKeypressEditor(msg)
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
 	case EntryListSelectedMsg:
 		m.entry = msg.Entry
+		m.err = nil
+		if msg.Entry == nil {
+			m.name.Reset()
+			m.start.Reset()
+			m.end.Reset()
+			m.content.Reset()
+			return m, nil
+		}
 		log.Debugf("Update the editor with %s", msg.Entry.Content)
+		m.edit = Edit{Name: msg.Entry.Name, Start: msg.Entry.Start, End: msg.Entry.End, Content: msg.Entry.Content}
+		m.name.SetValue(msg.Entry.Name)
+		m.start.SetValue(m.parser.In(msg.Entry.Start).Format(m.layout.DateTime()))
+		if msg.Entry.End != nil {
+			m.end.SetValue(m.parser.In(*msg.Entry.End).Format(m.layout.DateTime()))
+		} else {
+			m.end.SetValue("")
+		}
 		m.content.SetValue(msg.Entry.Content)
+		if m.focus == endField && m.running() {
+			m = m.focusField(contentField)
+		}
 		return m, nil
 
 	}
 	return m, nil
 }
 func (m Model) View() string {
-	return m.content.View()
+	// the zone of the entry's own start, which differs from today's across DST changes
+	zone, _ := time.Now().Zone()
+	if m.entry != nil {
+		zone, _ = m.parser.In(m.edit.Start).Zone()
+	}
+	label := func(f field, s string) string {
+		if m.focus == f {
+			return m.theme.AccentStyle().Render(fmt.Sprintf("%-6s", s))
+		}
+		return m.theme.SubtextStyle().Render(fmt.Sprintf("%-6s", s))
+	}
+	end := m.end.View()
+	if m.running() {
+		end = m.theme.SubtextStyle().Render("running")
+	}
+	lines := []string{
+		label(nameField, "name") + " " + m.name.View(),
+		label(startField, "start") + " " + m.start.View() + " " + m.theme.SubtextStyle().Render(zone),
+		label(endField, "end") + " " + end,
+	}
+	if m.err != nil {
+		lines = append(lines, lipgloss.NewStyle().Foreground(m.theme.AltAccent()).Render(m.err.Error()))
+	}
+	lines = append(lines, m.content.View())
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
 }
 
 func (m Model) StatusBar() string {
-	return "<tab> current task"
+	return keys.Status(m.keys.NextField, m.keys.PrevField)
 }
 
 func (m Model) handleKeypressEditor(msg tea.KeyMsg) (Model, tea.Cmd) {
-	v, _ := m.content.Update(msg)
-	m.content = v
-	m.entry.Content = v.Value()
-	return m, func() tea.Msg {
-		return EntryEditedMsg{
-			Entry: m.entry,
+	if m.entry == nil {
+		return m, nil
+	}
+	switch {
+	case key.Matches(msg, m.keys.NextField):
+		return m.focusField(m.nextField(1)), nil
+	case key.Matches(msg, m.keys.PrevField):
+		return m.focusField(m.nextField(-1)), nil
+	case key.Matches(msg, m.keys.Accept, m.keys.FormDown):
+		if m.focus != contentField {
+			return m.focusField(m.nextField(1)), nil
+		}
+	case key.Matches(msg, m.keys.FormUp):
+		if m.focus != contentField {
+			return m.focusField(m.nextField(-1)), nil
+		}
+	}
+
+	var cmd tea.Cmd
+	switch m.focus {
+	case nameField:
+		m.name, cmd = m.name.Update(msg)
+		// an empty name is kept in the input but never reaches the entry
+		name := strings.TrimSpace(m.name.Value())
+		if name == "" {
+			m.err = errors.New("name is missing")
+			return m, cmd
+		}
+		m.err = nil
+		m.edit.Name = name
+	case startField, endField:
+		if m.focus == startField {
+			m.start, cmd = m.start.Update(msg)
+		} else {
+			m.end, cmd = m.end.Update(msg)
+		}
+		// invalid times are kept in the input but never reach the entry
+		start, end, err := m.parseTimes()
+		m.err = err
+		if err != nil {
+			return m, cmd
+		}
+		m.edit.Start = start
+		m.edit.End = end
+	case contentField:
+		m.content, cmd = m.content.Update(msg)
+		m.edit.Content = m.content.Value()
+	}
+	edited := EntryEditedMsg{Entry: m.entry, Edit: m.edit}
+	return m, tea.Batch(cmd, func() tea.Msg {
+		return edited
+	})
+}
+
+// parseTimes reads start and end in the configured time zone. Times without
+// a date take the date of the start, the end can also be a duration like 1h30m.
+func (m Model) parseTimes() (time.Time, *time.Time, error) {
+	now := time.Now()
+	start, err := m.parser.Time(m.start.Value(), m.entry.Start, now)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("start: %w", err)
+	}
+	var end *time.Time
+	if !m.running() {
+		e, err := m.parser.Time(m.end.Value(), start, now)
+		if err != nil {
+			d, derr := m.parser.Duration(m.end.Value())
+			if derr != nil {
+				return time.Time{}, nil, fmt.Errorf("end: %w", err)
+			}
+			e = start.Add(d)
 		}
+		end = &e
+	}
+	return start, end, models.ValidateTimes(start, end, now)
+}
+
+func (m Model) running() bool {
+	return m.entry != nil && m.entry.End == nil
+}
+
+func (m Model) nextField(step int) field {
+	f := (int(m.focus) + step + 4) % 4
+	if field(f) == endField && m.running() {
+		f = (f + step + 4) % 4
 	}
+	return field(f)
+}
+
+func (m Model) focusField(f field) Model {
+	m.name.Blur()
+	m.start.Blur()
+	m.end.Blur()
+	m.content.Blur()
+	switch f {
+	case nameField:
+		m.name.Focus()
+	case startField:
+		m.start.Focus()
+	case endField:
+		m.end.Focus()
+	case contentField:
+		m.content.Focus()
+	}
+	m.focus = f
+	return m
+}
+
+// Entry returns the entry being edited, nil if there is none.
+func (m Model) Entry() *models.Entry {
+	return m.entry
 }
 
 func (m Model) EditorView() string {
@@ -1,15 +1,24 @@
 package list
 
 import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
 	bl "github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
 	"github.com/danielroehrig/timekeeper/models"
 	"github.com/danielroehrig/timekeeper/themes"
 )
 
 type Model struct {
-	list  bl.Model
-	theme themes.Theme
+	list          bl.Model
+	delegate      EntryListDelegate
+	confirmDelete *models.Entry
+	lastDeleted   *models.Entry
+	keys          keys.KeyMap
 }
 
 type EntriesLoadedMsg struct {
@@ -22,41 +31,159 @@ type AddEntryMsg struct {
 	Entry *models.Entry
 }
 
+// EntryMovedMsg puts an entry whose start changed back in order.
+type EntryMovedMsg struct {
+	Entry *models.Entry
+}
+
+// DeleteEntryMsg is sent after the user confirmed deleting an entry, it is already removed from the list.
+type DeleteEntryMsg struct {
+	Entry *models.Entry
+}
+
+// RestoreEntryMsg undoes the last deletion, the entry is back in the list but not yet in the database.
+type RestoreEntryMsg struct {
+	Entry *models.Entry
+}
+
+// DuplicateEntryMsg carries a new, unsaved copy of the selected entry.
+type DuplicateEntryMsg struct {
+	Entry *models.Entry
+}
+
+// NewEntryMsg asks for the form to add a past entry.
+type NewEntryMsg struct{}
+
+// ContinueEntryMsg asks to start a new running task like the selected entry.
+type ContinueEntryMsg struct {
+	Entry *models.Entry
+}
+
+// SwitchToEntryMsg asks to stop the running task and continue the selected entry at the same moment.
+type SwitchToEntryMsg struct {
+	Entry *models.Entry
+}
+
 func (m Model) Init() tea.Cmd {
 	return nil
 }
 
-func New(theme themes.Theme) Model {
-	delegates := NewEntryListDelegate(theme)
-	entryList := bl.New(nil, delegates, 40, 10)
+func New(theme themes.Theme, layout models.Layout, rounding models.Rounding, km keys.KeyMap) Model {
+	delegate := NewEntryListDelegate(theme, layout, rounding)
+	entryList := bl.New(nil, delegate, 40, 10)
+	entryList.Filter = filterEntries
+	entryList.KeyMap = listKeyMap(km)
 	return Model{
-		list:  entryList,
-		theme: theme,
+		list:     entryList,
+		delegate: delegate,
+		keys:     km,
 	}
 }
 
+// listKeyMap takes the filter key from the key map and turns off the list's
+// own quit and help keys, quitting is left to the app so changes get saved.
+func listKeyMap(km keys.KeyMap) bl.KeyMap {
+	lk := bl.DefaultKeyMap()
+	lk.Filter = km.Filter
+	lk.Quit.SetEnabled(false)
+	lk.ForceQuit.SetEnabled(false)
+	lk.ShowFullHelp.SetEnabled(false)
+	lk.CloseFullHelp.SetEnabled(false)
+	return lk
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKeypressTaskList(msg)
 	case EntriesLoadedMsg:
-		m.list = convertEntriesToList(msg.Entries, m.theme)
+		m.list = convertEntriesToList(msg.Entries, m.delegate, m.keys)
 		return m, nil
 	case AddEntryMsg:
-		return m, m.list.InsertItem(0, msg.Entry)
+		return m, m.insertSorted(msg.Entry)
+	case EntryMovedMsg:
+		return m, m.moveEntry(msg.Entry)
+	case themes.ChangedMsg:
+		m.delegate.theme = msg.Theme
+		m.list.SetDelegate(m.delegate)
 	case bl.FilterMatchesMsg:
 		m.list, _ = m.list.Update(msg)
 	}
 	return m, nil
 }
 func (m Model) View() string {
+	if m.confirmDelete != nil {
+		prompt := m.delegate.theme.AccentStyle().Render("delete " + m.confirmDelete.Name + "? (" + m.keys.Confirm.Help().Key + "/n)")
+		return lipgloss.JoinVertical(lipgloss.Left, prompt, m.list.View())
+	}
 	return m.list.View()
 }
 
 func (m Model) handleKeypressTaskList(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if m.confirmDelete != nil {
+		e := m.confirmDelete
+		m.confirmDelete = nil
+		if !key.Matches(msg, m.keys.Confirm) {
+			return m, nil
+		}
+		m.lastDeleted = e
+		cmd := m.removeEntry(e)
+		return m, tea.Batch(cmd, func() tea.Msg {
+			return DeleteEntryMsg{Entry: e}
+		})
+	}
+	selected, _ := m.list.SelectedItem().(*models.Entry)
+	if m.list.SettingFilter() {
+		selected = nil
+	}
+	switch {
+	case key.Matches(msg, m.keys.Add):
+		if !m.list.SettingFilter() {
+			return m, func() tea.Msg {
+				return NewEntryMsg{}
+			}
+		}
+	case key.Matches(msg, m.keys.Delete):
+		if selected != nil {
+			m.confirmDelete = selected
+			return m, nil
+		}
+	case key.Matches(msg, m.keys.Undo):
+		if m.lastDeleted != nil && !m.list.SettingFilter() {
+			e := m.lastDeleted
+			m.lastDeleted = nil
+			cmd := m.insertSorted(e)
+			return m, tea.Batch(cmd, func() tea.Msg {
+				return RestoreEntryMsg{Entry: e}
+			})
+		}
+	case key.Matches(msg, m.keys.Duplicate):
+		if selected != nil {
+			dup := *selected
+			dup.ObjectId = ""
+			dup.Tags = append([]string(nil), selected.Tags...)
+			dup.Pauses = append([]models.Pause(nil), selected.Pauses...)
+			cmd := m.insertSorted(&dup)
+			return m, tea.Batch(cmd, func() tea.Msg {
+				return DuplicateEntryMsg{Entry: &dup}
+			})
+		}
+	case key.Matches(msg, m.keys.Continue):
+		if selected != nil {
+			return m, func() tea.Msg {
+				return ContinueEntryMsg{Entry: selected}
+			}
+		}
+	case key.Matches(msg, m.keys.SwitchTo):
+		if selected != nil {
+			return m, func() tea.Msg {
+				return SwitchToEntryMsg{Entry: selected}
+			}
+		}
+	}
 	v, cmd := m.list.Update(msg)
 	m.list = v
-	if msg.Type == tea.KeyEnter {
+	if key.Matches(msg, m.keys.Edit) {
 		return m, tea.Batch(cmd, func() tea.Msg {
 			return EntrySelectedMsg{}
 		})
@@ -64,12 +191,14 @@ func (m Model) handleKeypressTaskList(msg tea.KeyMsg) (Model, tea.Cmd) {
 	return m, cmd
 }
 
-func convertEntriesToList(entries []*models.Entry, theme themes.Theme) bl.Model {
+func convertEntriesToList(entries []*models.Entry, delegate EntryListDelegate, km keys.KeyMap) bl.Model {
 	listEntries := make([]bl.Item, 0, len(entries))
 	for _, entry := range entries {
 		listEntries = append(listEntries, entry)
 	}
-	m := bl.New(listEntries, NewEntryListDelegate(theme), 40, 20)
+	m := bl.New(listEntries, delegate, 40, 20)
+	m.Filter = filterEntries
+	m.KeyMap = listKeyMap(km)
 	m.SetShowStatusBar(false)
 	m.SetShowTitle(false)
 	m.SetShowHelp(false)
@@ -77,5 +206,133 @@ func convertEntriesToList(entries []*models.Entry, theme themes.Theme) bl.Model
 }
 
 func (m Model) StatusBar() string {
-	return "see list"
+	if m.confirmDelete != nil {
+		return keys.Status(m.keys.Confirm) + " \uF444 <any> cancel"
+	}
+	bindings := []key.Binding{m.keys.Edit, m.keys.Add, m.keys.Delete, m.keys.Duplicate, m.keys.Continue, m.keys.SwitchTo, m.keys.Filter}
+	if m.lastDeleted != nil {
+		bindings = append(bindings, m.keys.Undo)
+	}
+	return keys.Status(bindings...)
+}
+
+// Entries returns all entries of the list, newest first.
+func (m Model) Entries() []*models.Entry {
+	entries := make([]*models.Entry, 0, len(m.list.Items()))
+	for _, item := range m.list.Items() {
+		if e, ok := item.(*models.Entry); ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Typing reports whether keys go into the filter input.
+func (m Model) Typing() bool {
+	return m.list.SettingFilter()
+}
+
+// insertSorted keeps the list ordered by start, newest first, and selects the inserted entry.
+func (m *Model) insertSorted(e *models.Entry) tea.Cmd {
+	items := m.list.Items()
+	index := len(items)
+	for i, item := range items {
+		if other, ok := item.(*models.Entry); ok && !other.Start.After(e.Start) {
+			index = i
+			break
+		}
+	}
+	cmd := m.list.InsertItem(index, e)
+	if !m.list.IsFiltered() {
+		m.list.Select(index)
+	}
+	return cmd
+}
+
+// moveEntry sorts e in again and keeps the selection where it was.
+func (m *Model) moveEntry(e *models.Entry) tea.Cmd {
+	selected := m.list.SelectedItem()
+	items := m.list.Items()
+	sorted := make([]bl.Item, 0, len(items))
+	for _, item := range items {
+		if item != bl.Item(e) {
+			sorted = append(sorted, item)
+		}
+	}
+	index := len(sorted)
+	for i, item := range sorted {
+		if other, ok := item.(*models.Entry); ok && !other.Start.After(e.Start) {
+			index = i
+			break
+		}
+	}
+	sorted = append(sorted[:index], append([]bl.Item{e}, sorted[index:]...)...)
+	cmd := m.list.SetItems(sorted)
+	for i, item := range m.list.VisibleItems() {
+		if item == selected {
+			m.list.Select(i)
+			break
+		}
+	}
+	return cmd
+}
+
+func (m *Model) removeEntry(e *models.Entry) tea.Cmd {
+	items := m.list.Items()
+	remaining := make([]bl.Item, 0, len(items))
+	for _, item := range items {
+		if item != bl.Item(e) {
+			remaining = append(remaining, item)
+		}
+	}
+	cmd := m.list.SetItems(remaining)
+	if m.list.Index() >= len(m.list.VisibleItems()) && m.list.Index() > 0 {
+		m.list.Select(m.list.Index() - 1)
+	}
+	return cmd
+}
+
+// filterEntries narrows the list to entries carrying all tags when the filter
+// consists of #tag terms and falls back to fuzzy matching otherwise.
+func filterEntries(term string, targets []string) []bl.Rank {
+	terms := strings.Fields(strings.ToLower(term))
+	if len(terms) == 0 {
+		return bl.DefaultFilter(term, targets)
+	}
+	for _, t := range terms {
+		if !strings.HasPrefix(t, "#") || len(t) == 1 {
+			return bl.DefaultFilter(term, targets)
+		}
+	}
+	var ranks []bl.Rank
+	for i, target := range targets {
+		lower := strings.ToLower(target)
+		var matched []int
+		for _, t := range terms {
+			idx := tagIndex(lower, t)
+			if idx < 0 {
+				matched = nil
+				break
+			}
+			for j := range utf8.RuneCountInString(t) {
+				matched = append(matched, idx+j)
+			}
+		}
+		if matched != nil {
+			ranks = append(ranks, bl.Rank{Index: i, MatchedIndexes: matched})
+		}
+	}
+	return ranks
+}
+
+// tagIndex returns the rune offset of a tag in target starting with prefix, or -1.
+func tagIndex(target, prefix string) int {
+	offset := 0
+	for _, word := range strings.SplitAfter(target, " ") {
+		if strings.HasPrefix(word, prefix) {
+			return offset
+		}
+		offset += utf8.RuneCountInString(word)
+	}
+	return -1
 }
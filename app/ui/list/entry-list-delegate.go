@@ -11,12 +11,16 @@ import (
 )
 
 type EntryListDelegate struct {
-	theme themes.Theme
+	theme    themes.Theme
+	layout   models.Layout
+	rounding models.Rounding
 }
 
-func NewEntryListDelegate(theme themes.Theme) EntryListDelegate {
+func NewEntryListDelegate(theme themes.Theme, layout models.Layout, rounding models.Rounding) EntryListDelegate {
 	return EntryListDelegate{
-		theme: theme,
+		theme:    theme,
+		layout:   layout,
+		rounding: rounding,
 	}
 }
 
@@ -31,7 +35,7 @@ func (d EntryListDelegate) Render(w io.Writer, m list.Model, index int, item lis
 	}
 	dur := 0 * time.Second
 	if e.End != nil {
-		dur = e.End.Sub(e.Start)
+		dur = d.rounding.Round(e.Duration(*e.End))
 	}
 	now := time.Now()
 	var dateString string
@@ -41,21 +45,27 @@ func (d EntryListDelegate) Render(w io.Writer, m list.Model, index int, item lis
 	} else if e.Start.YearDay() == now.YearDay()-1 && e.Start.Year() == now.Year() {
 		dateString = "yesterday"
 	} else {
-		dateString = e.Start.Format("2006-01-02")
+		dateString = e.Start.Format(d.layout.Date)
 	}
 	var endTime string
 	if e.End != nil {
-		endTime = e.End.Format("15:04")
+		endTime = e.End.Format(d.layout.Time)
 	} else {
 		endTime = "unknown"
 	}
-	dateString = d.theme.SubtextStyle().Render(fmt.Sprintf("%s %s - %s: %s", dateString, e.Start.Format("15:04"), endTime, dur.Round(time.Minute)))
+	dateString = d.theme.SubtextStyle().Render(fmt.Sprintf("%s %s - %s: %s", dateString, e.Start.Format(d.layout.Time), endTime, dur.Round(time.Minute)))
 	var taskString string
 	if m.Index() == index {
 		taskString = d.theme.AccentStyle().Render(e.Name)
 	} else {
 		taskString = d.theme.NormalStyle().Render(e.Name)
 	}
+	if e.Project != nil {
+		taskString += " " + d.theme.SubtextStyle().Render("@"+e.Project.String())
+	}
+	if tags := e.TagString(); tags != "" {
+		taskString += " " + d.theme.SubtextStyle().Render(tags)
+	}
 
 	fmt.Fprintf(w, "%s\n%s", dateString, taskString)
 }
@@ -69,9 +79,13 @@ func (d EntryListDelegate) Spacing() int {
 }
 
 func (d EntryListDelegate) Update(_ tea.Msg, m *list.Model) tea.Cmd {
+	selected, ok := m.SelectedItem().(*models.Entry)
+	if !ok {
+		return nil
+	}
 	return func() tea.Msg {
 		return EntryChangedMsg{
-			SelectedEntry: m.SelectedItem().(*models.Entry),
+			SelectedEntry: selected,
 		}
 	}
 }
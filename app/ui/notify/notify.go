@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/themes"
+)
+
+type Severity byte
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Msg shows a notification in the status bar. Any component can send it.
+// Notifications with a Retry command stay until they are retried or dismissed.
+type Msg struct {
+	Severity Severity
+	Text     string
+	Retry    tea.Cmd
+}
+
+type dismissMsg struct {
+	id int
+}
+
+// how long a notification is shown, by severity
+var timeouts = []time.Duration{4 * time.Second, 6 * time.Second, 10 * time.Second}
+
+const historySize = 100
+
+func send(msg Msg) tea.Cmd {
+	return func() tea.Msg {
+		return msg
+	}
+}
+
+func Infof(format string, args ...interface{}) tea.Cmd {
+	return send(Msg{Severity: Info, Text: fmt.Sprintf(format, args...)})
+}
+
+func Warnf(format string, args ...interface{}) tea.Cmd {
+	return send(Msg{Severity: Warning, Text: fmt.Sprintf(format, args...)})
+}
+
+func Errorf(format string, args ...interface{}) tea.Cmd {
+	return send(Msg{Severity: Error, Text: fmt.Sprintf(format, args...)})
+}
+
+// Retryable reports an error that goes away by running retry again.
+func Retryable(retry tea.Cmd, format string, args ...interface{}) tea.Cmd {
+	return send(Msg{Severity: Error, Text: fmt.Sprintf(format, args...), Retry: retry})
+}
+
+type notification struct {
+	id int
+	at time.Time
+	Msg
+}
+
+// Model keeps the shown notification and the history of all notifications of this session.
+type Model struct {
+	history []notification // oldest first
+	current int            // id of the shown notification, 0 for none
+	nextID  int
+	theme   themes.Theme
+	keys    keys.KeyMap
+	layout  string
+}
+
+func New(theme themes.Theme, km keys.KeyMap, timeLayout string) Model {
+	return Model{
+		nextID: 1,
+		theme:  theme,
+		keys:   km,
+		layout: timeLayout,
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case Msg:
+		n := notification{id: m.nextID, at: time.Now(), Msg: msg}
+		m.nextID++
+		m.history = append(m.history, n)
+		if len(m.history) > historySize {
+			m.history = m.history[len(m.history)-historySize:]
+		}
+		m.current = n.id
+		if msg.Retry != nil {
+			return m, nil
+		}
+		return m, tea.Tick(timeouts[msg.Severity], func(time.Time) tea.Msg {
+			return dismissMsg{id: n.id}
+		})
+	case dismissMsg:
+		if m.current == msg.id {
+			m.current = 0
+		}
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
+	}
+	return m, nil
+}
+
+func (m Model) shown() (notification, bool) {
+	if m.current == 0 {
+		return notification{}, false
+	}
+	for _, n := range m.history {
+		if n.id == m.current {
+			return n, true
+		}
+	}
+	return notification{}, false
+}
+
+// Active reports whether a notification is shown.
+func (m Model) Active() bool {
+	_, ok := m.shown()
+	return ok
+}
+
+// Retry hides the shown notification and runs its retry command, if it has one.
+func (m Model) Retry() (Model, tea.Cmd) {
+	n, ok := m.shown()
+	if !ok || n.Retry == nil {
+		return m, nil
+	}
+	m.current = 0
+	return m, n.Retry
+}
+
+// Dismiss hides the shown notification.
+func (m Model) Dismiss() Model {
+	m.current = 0
+	return m
+}
+
+func (m Model) style(s Severity) lipgloss.Style {
+	switch s {
+	case Warning:
+		return m.theme.AccentStyle()
+	case Error:
+		return lipgloss.NewStyle().Foreground(m.theme.AltAccent()).Bold(true)
+	default:
+		return m.theme.NormalStyle()
+	}
+}
+
+// View renders the shown notification for the status bar, empty if there is none.
+func (m Model) View() string {
+	n, ok := m.shown()
+	if !ok {
+		return ""
+	}
+	hint := keys.Status(m.keys.Dismiss, m.keys.History)
+	if n.Retry != nil {
+		hint = keys.Status(m.keys.Retry, m.keys.Dismiss, m.keys.History)
+	}
+	return m.style(n.Severity).Render(n.Text) + m.theme.SubtextStyle().Render(" \uF444 "+hint)
+}
+
+// HistoryView lists the notifications of this session, newest first.
+func (m Model) HistoryView(height int) string {
+	lines := []string{m.theme.AccentStyle().Render("Notifications")}
+	if len(m.history) == 0 {
+		lines = append(lines, m.theme.SubtextStyle().Render("nothing happened yet"))
+	}
+	for i := len(m.history) - 1; i >= 0 && len(lines) < height; i-- {
+		n := m.history[i]
+		lines = append(lines, m.theme.SubtextStyle().Render(fmt.Sprintf("%s %-7s ", n.at.Format(m.layout), n.Severity))+m.style(n.Severity).Render(n.Text))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
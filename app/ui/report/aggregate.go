@@ -0,0 +1,119 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+type Period byte
+
+const (
+	Day Period = iota
+	Week
+	Month
+)
+
+type GroupBy byte
+
+const (
+	ByName GroupBy = iota
+	ByProject
+	ByTag
+)
+
+type Row struct {
+	Label    string
+	Duration time.Duration
+	Percent  float64
+}
+
+func (g GroupBy) String() string {
+	switch g {
+	case ByProject:
+		return "project"
+	case ByTag:
+		return "tag"
+	default:
+		return "name"
+	}
+}
+
+// Bounds returns the period containing now shifted by offset periods, e.g. -1 for last week.
+// Weeks start on weekStart.
+func Bounds(p Period, offset int, now time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch p {
+	case Week:
+		back := (int(day.Weekday()) - int(weekStart) + 7) % 7
+		from := day.AddDate(0, 0, -back+7*offset)
+		return from, from.AddDate(0, 0, 7)
+	case Month:
+		from := time.Date(y, m+time.Month(offset), 1, 0, 0, 0, 0, now.Location())
+		return from, from.AddDate(0, 1, 0)
+	default:
+		from := day.AddDate(0, 0, offset)
+		return from, from.AddDate(0, 0, 1)
+	}
+}
+
+// Aggregate sums up the part of every entry that falls into [from, to), each
+// part rounded on its own. Pauses don't count, running entries count until
+// now. With ByTag an entry counts for each of its tags, so the percentages can
+// add up to more than 100.
+func Aggregate(entries []*models.Entry, from, to, now time.Time, groupBy GroupBy, rounding models.Rounding) ([]Row, time.Duration) {
+	sums := map[string]time.Duration{}
+	var total time.Duration
+	for _, e := range entries {
+		active := e.DurationBetween(from, to, now)
+		if active <= 0 {
+			continue
+		}
+		dur := rounding.Round(active)
+		total += dur
+		for _, label := range labels(e, groupBy) {
+			sums[label] += dur
+		}
+	}
+	rows := make([]Row, 0, len(sums))
+	for label, dur := range sums {
+		row := Row{Label: label, Duration: dur}
+		if total > 0 {
+			row.Percent = float64(dur) / float64(total) * 100
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Duration == rows[j].Duration {
+			return rows[i].Label < rows[j].Label
+		}
+		return rows[i].Duration > rows[j].Duration
+	})
+	return rows, total
+}
+
+func labels(e *models.Entry, groupBy GroupBy) []string {
+	switch groupBy {
+	case ByProject:
+		if e.Project == nil {
+			return []string{"(no project)"}
+		}
+		return []string{e.Project.String()}
+	case ByTag:
+		if len(e.Tags) == 0 {
+			return []string{"(untagged)"}
+		}
+		tags := make([]string, 0, len(e.Tags))
+		for _, t := range e.Tags {
+			tags = append(tags, "#"+t)
+		}
+		return tags
+	default:
+		if e.Name == "" {
+			return []string{"(unnamed)"}
+		}
+		return []string{e.Name}
+	}
+}
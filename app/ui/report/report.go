@@ -0,0 +1,183 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/models"
+	"github.com/danielroehrig/timekeeper/themes"
+)
+
+type EntriesLoadedMsg struct {
+	Entries []*models.Entry
+}
+
+type AddEntryMsg struct {
+	Entry *models.Entry
+}
+
+type RemoveEntryMsg struct {
+	Entry *models.Entry
+}
+
+// ExportMsg asks for the shown period to be exported.
+type ExportMsg struct {
+	From   time.Time
+	To     time.Time
+	Format string
+}
+
+// ExportedMsg reports the result of an export, the app shows it as a notification.
+type ExportedMsg struct {
+	Path  string
+	Count int
+	Err   error
+}
+
+var exportFormats = []string{"csv", "json", "ics"}
+
+type Model struct {
+	entries   []*models.Entry
+	period    Period
+	groupBy   GroupBy
+	offset    int
+	weekStart time.Weekday
+	rounding  models.Rounding
+	layout    models.Layout
+	theme     themes.Theme
+	width     int
+	format    int // index into exportFormats
+	keys      keys.KeyMap
+}
+
+func New(theme themes.Theme, weekStart time.Weekday, rounding models.Rounding, layout models.Layout, km keys.KeyMap) Model {
+	return Model{
+		period:    Week,
+		groupBy:   ByName,
+		weekStart: weekStart,
+		rounding:  rounding,
+		layout:    layout,
+		theme:     theme,
+		width:     40,
+		keys:      km,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.handleKeypressReport(msg)
+	case tea.WindowSizeMsg:
+		m.width = msg.Width/2 - 4
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
+	case EntriesLoadedMsg:
+		m.entries = msg.Entries
+	case RemoveEntryMsg:
+		entries := make([]*models.Entry, 0, len(m.entries))
+		for _, e := range m.entries {
+			if e != msg.Entry {
+				entries = append(entries, e)
+			}
+		}
+		m.entries = entries
+	case AddEntryMsg:
+		for _, e := range m.entries {
+			if e == msg.Entry {
+				return m, nil
+			}
+		}
+		m.entries = append(m.entries, msg.Entry)
+	}
+	return m, nil
+}
+
+func (m Model) handleKeypressReport(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.PrevPeriod):
+		m.offset--
+	case key.Matches(msg, m.keys.NextPeriod):
+		if m.offset < 0 {
+			m.offset++
+		}
+	case key.Matches(msg, m.keys.Day):
+		m.period, m.offset = Day, 0
+	case key.Matches(msg, m.keys.Week):
+		m.period, m.offset = Week, 0
+	case key.Matches(msg, m.keys.Month):
+		m.period, m.offset = Month, 0
+	case key.Matches(msg, m.keys.GroupBy):
+		m.groupBy = (m.groupBy + 1) % 3
+	case key.Matches(msg, m.keys.ExportFormat):
+		m.format = (m.format + 1) % len(exportFormats)
+	case key.Matches(msg, m.keys.Export):
+		from, to := Bounds(m.period, m.offset, time.Now(), m.weekStart)
+		format := exportFormats[m.format]
+		return m, func() tea.Msg {
+			return ExportMsg{From: from, To: to, Format: format}
+		}
+	}
+	return m, nil
+}
+
+func (m Model) View() string {
+	now := time.Now()
+	from, to := Bounds(m.period, m.offset, now, m.weekStart)
+	rows, total := Aggregate(m.entries, from, to, now, m.groupBy, m.rounding)
+
+	title := m.theme.AccentStyle().Render(m.title(from, to)) +
+		m.theme.SubtextStyle().Render(" by "+m.groupBy.String())
+	lines := []string{title}
+	if len(rows) == 0 {
+		lines = append(lines, m.theme.SubtextStyle().Render("nothing tracked"))
+	}
+	labelWidth := m.width - 18
+	if labelWidth < 8 {
+		labelWidth = 8
+	}
+	for _, r := range rows {
+		label := truncate(r.Label, labelWidth)
+		lines = append(lines, m.theme.NormalStyle().Render(fmt.Sprintf("%-*s %8s %6.1f%%", labelWidth, label, formatDuration(r.Duration), r.Percent)))
+	}
+	lines = append(lines, m.theme.AccentStyle().Render(fmt.Sprintf("%-*s %8s", labelWidth, "Total", formatDuration(total))))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m Model) StatusBar() string {
+	export := m.keys.Export
+	export.SetHelp(export.Help().Key, "export "+exportFormats[m.format])
+	return keys.Status(m.keys.PrevPeriod, m.keys.NextPeriod, m.keys.Day, m.keys.Week, m.keys.Month, m.keys.GroupBy, export, m.keys.ExportFormat)
+}
+
+func (m Model) title(from, to time.Time) string {
+	switch m.period {
+	case Week:
+		return fmt.Sprintf("Week %s - %s", from.Format(m.layout.Date), to.AddDate(0, 0, -1).Format(m.layout.Date))
+	case Month:
+		return from.Format("January 2006")
+	default:
+		return from.Format("Monday " + m.layout.Date)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return strings.TrimSpace(string(r[:width-1])) + "…"
+}
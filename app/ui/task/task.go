@@ -1,29 +1,100 @@
 package task
 
 import (
+	"fmt"
 	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/app/ui/notify"
 	"github.com/danielroehrig/timekeeper/models"
 	"github.com/danielroehrig/timekeeper/themes"
+	"github.com/danielroehrig/timekeeper/timeexpr"
+	"strings"
 	"time"
 )
 
+// StartRunningMsg starts RunningTask. With Trim a new task started in the
+// past may cut the entry before it short.
 type StartRunningMsg struct {
 	RunningTask *models.Entry
+	Trim        bool
 }
 type StopRunningTaskMsg struct{}
+
+// PastEntryMsg carries a finished entry typed with its times, like
+// "standup yesterday 14:00-15:30". With Trim it may cut the entry before it short.
+type PastEntryMsg struct {
+	Entry *models.Entry
+	Trim  bool
+}
+
+// SwitchTaskMsg asks to stop the running task and start Next at the same
+// moment. The start of Next is set when the switch happens.
+type SwitchTaskMsg struct {
+	Next *models.Entry
+}
+
+// AdjustStartMsg asks to move the start of the running task. With Trim the
+// previous entry may be cut short where it overlaps.
+type AdjustStartMsg struct {
+	Start time.Time
+	Trim  bool
+}
+
+// TrimNeededMsg asks to confirm cutting Previous short to let an entry start
+// at Start. Confirmed is sent again with Trim set once confirmed.
+type TrimNeededMsg struct {
+	Start     time.Time
+	Previous  *models.Entry
+	Confirmed tea.Msg
+}
+
+// StartAdjustedMsg tells that the running task started at From before.
+type StartAdjustedMsg struct {
+	From time.Time
+}
+
+// PauseRunningTaskMsg pauses the running task, or resumes it if it is paused.
+type PauseRunningTaskMsg struct{}
 type EditRunningTaskMsg struct{}
+type ProjectsLoadedMsg struct {
+	Projects []*models.Project
+}
+
+// EntriesLoadedMsg carries the finished entries to count today's pomodoros.
+type EntriesLoadedMsg struct {
+	Entries []*models.Entry
+}
+
+// PhaseEndedMsg is sent when a Pomodoro interval is over. The running entry
+// ends At and Next starts at the same time, a nil Next just stops it.
+type PhaseEndedMsg struct {
+	At   time.Time
+	Next *models.Entry
+	Text string
+}
+
+// PhaseTickMsg fires at the end of an interval, ticks of an earlier interval are ignored.
+type PhaseTickMsg struct {
+	id int
+}
 
 type state byte
 
 const (
 	input state = iota
 	running
+	switching   // typing the next task while one is running
+	adjusting   // typing a new start for the running task
+	confirmTrim // asking to cut the previous entry short
 )
 
+const placeholder = "Tell me what you are doing"
+
 type Model struct {
 	state       state
 	task        textinput.Model
@@ -32,9 +103,25 @@ type Model struct {
 	width       int
 	theme       themes.Theme
 	spinner     spinner.Model
+	projects    []*models.Project
+	project     int // index into projects, -1 for no project
+	keys        keys.KeyMap
+	layout      models.Layout
+	parser      timeexpr.Parser
+	trim        TrimNeededMsg
+	trimFrom    state // the state to return to from confirmTrim
+
+	pomodoro   models.Pomodoro
+	pomodoroOn bool
+	phaseStart time.Time // start of the running interval, zero if not counting down
+	phaseId    int
+	lastWork   *models.Entry // the task continued after a break
+	next       *models.Entry // the entry started by the last PhaseEndedMsg
+	finished   int           // pomodoros finished on finishedOn's day
+	finishedOn time.Time
 }
 
-func New(theme themes.Theme) Model {
+func New(theme themes.Theme, layout models.Layout, parser timeexpr.Parser, km keys.KeyMap, pomodoro models.Pomodoro, pomodoroOn bool) Model {
 	i := textinput.New()
 	i.Prompt = " "
 	s := spinner.New()
@@ -48,8 +135,14 @@ func New(theme themes.Theme) Model {
 		width:       10,    // might be needed to tweak max input characters or placeholder message
 		theme:       theme, // might be needed to style inner components
 		spinner:     s,
+		project:     -1,
+		keys:        km,
+		layout:      layout,
+		parser:      parser,
+		pomodoro:    pomodoro,
+		pomodoroOn:  pomodoroOn,
 	}
-	m.task.Placeholder = "Tell me what you are doing"
+	m.task.Placeholder = placeholder
 	m.task.Focus()
 	return m
 }
@@ -70,33 +163,146 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, cmd
 	case StartRunningMsg:
 		m.state = running
+		m.task.Placeholder = placeholder
 		m.runningTask = msg.RunningTask
+		if msg.RunningTask != m.next && msg.RunningTask.IsBreak() {
+			// a break restored from the database follows a pomodoro finished earlier
+			m.countPomodoro(msg.RunningTask.Start)
+		}
+		if !msg.RunningTask.IsBreak() {
+			m.lastWork = msg.RunningTask
+		}
+		if m.pomodoroOn {
+			// only the entries of the mode itself continue an interval, a
+			// restored or backdated task counts down from now instead of
+			// being cut off in the past
+			start := msg.RunningTask.Start
+			if now := time.Now(); msg.RunningTask != m.next && start.Before(now) {
+				start = now
+			}
+			return m, m.startPhase(start)
+		}
 		return m, nil
 	case StopRunningTaskMsg:
 		m.state = input
 		m.task.Reset()
+		m.task.Placeholder = placeholder
 		m.runningTask = nil
+		m.phaseStart = time.Time{}
+		return m, nil
+	case TrimNeededMsg:
+		if m.state != confirmTrim {
+			m.trimFrom = m.state
+		}
+		m.state = confirmTrim
+		m.trim = msg
+		return m, nil
+	case PastEntryMsg:
+		m.task.Reset()
+		return m, nil
+	case StartAdjustedMsg:
+		// an interval that began with the task moves along with a later start,
+		// an earlier start would cut it off in the past
+		if !m.phaseStart.IsZero() && m.phaseStart.Equal(msg.From) && m.runningTask != nil && m.runningTask.Start.After(m.phaseStart) {
+			return m, m.startPhase(m.runningTask.Start)
+		}
+		return m, nil
+	case PauseRunningTaskMsg:
+		if m.phaseStart.IsZero() || m.runningTask == nil {
+			return m, nil
+		}
+		// the interval is stretched by the pause, the tick is due again on resume
+		m.phaseId++
+		if m.runningTask.Paused() {
+			return m, nil
+		}
+		return m, m.tick(time.Until(m.phaseEnd(time.Now())))
+	case EntriesLoadedMsg:
+		now := time.Now()
+		m.finished, m.finishedOn = models.CountPomodoros(msg.Entries, now), now
+		// the running break was counted when it started and is not among the finished entries
+		if r := m.runningTask; r != nil && r.IsBreak() && sameDay(r.Start, now) {
+			m.finished++
+		}
+		return m, nil
+	case PhaseTickMsg:
+		if msg.id != m.phaseId || m.phaseStart.IsZero() || m.runningTask == nil || m.runningTask.Paused() {
+			return m, nil
+		}
+		if wait := time.Until(m.phaseEnd(time.Now())); wait > 0 {
+			return m, m.tick(wait)
+		}
+		return m, m.endPhase()
+	case themes.ChangedMsg:
+		m.theme = msg.Theme
+		m.spinner.Style = lipgloss.NewStyle().Foreground(msg.Theme.Accent())
 		return m, nil
 	case spinner.TickMsg:
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
+	case ProjectsLoadedMsg:
+		selected := m.selectedProject()
+		m.projects = msg.Projects
+		m.project = -1
+		for i, p := range m.projects {
+			if selected != nil && p.ObjectId == selected.ObjectId {
+				m.project = i
+			}
+		}
+		return m, nil
 	}
 	return m, nil
 }
 
 func (m Model) handleKeypressTaskInput(msg tea.KeyMsg) (Model, tea.Cmd) {
-	key := msg.Type
-	if m.state == input {
-		switch key {
-		case tea.KeyEnter:
-			return m, func() tea.Msg {
-				runningTask := &models.Entry{
-					Start: time.Now(),
-					End:   nil,
-					Name:  m.task.Value(),
+	if key.Matches(msg, m.keys.Pomodoro) {
+		return m, m.togglePomodoro()
+	}
+	switch m.state {
+	case adjusting:
+		return m.handleKeypressAdjust(msg)
+	case confirmTrim:
+		m.state = m.trimFrom
+		if !key.Matches(msg, m.keys.Confirm) {
+			return m, nil
+		}
+		confirmed := m.trim.Confirmed
+		return m, func() tea.Msg {
+			return confirmed
+		}
+	}
+	if m.state == switching && key.Matches(msg, m.keys.Cancel) {
+		m.state = running
+		m.task.Reset()
+		return m, nil
+	}
+	if m.state != running {
+		switch {
+		case key.Matches(msg, m.keys.Start):
+			if m.state == switching {
+				name, project, tags := models.ParseInput(m.task.Value())
+				if project == nil {
+					project = m.selectedProject()
 				}
-				return StartRunningMsg{RunningTask: runningTask}
+				return m, func() tea.Msg {
+					return SwitchTaskMsg{Next: &models.Entry{Name: name, Project: project, Tags: tags}}
+				}
+			}
+			return m.startTyped()
+		case key.Matches(msg, m.keys.ProjectPrev):
+			if m.project >= 0 {
+				m.project--
+			} else {
+				m.project = len(m.projects) - 1
 			}
+			return m, nil
+		case key.Matches(msg, m.keys.ProjectNext):
+			if m.project < len(m.projects)-1 {
+				m.project++
+			} else {
+				m.project = -1
+			}
+			return m, nil
 		default:
 			v, cmd := m.task.Update(msg)
 			m.task = v
@@ -104,12 +310,25 @@ func (m Model) handleKeypressTaskInput(msg tea.KeyMsg) (Model, tea.Cmd) {
 		}
 
 	} else {
-		switch key {
-		case tea.KeySpace:
+		switch {
+		case key.Matches(msg, m.keys.Stop):
 			return m, func() tea.Msg {
 				return StopRunningTaskMsg{}
 			}
-		case tea.KeyEnter:
+		case key.Matches(msg, m.keys.Pause):
+			return m, func() tea.Msg {
+				return PauseRunningTaskMsg{}
+			}
+		case key.Matches(msg, m.keys.Switch):
+			m.state = switching
+			m.task.Reset()
+			return m, m.task.Focus()
+		case key.Matches(msg, m.keys.AdjustStart):
+			m.state = adjusting
+			m.task.Reset()
+			m.task.Placeholder = "-10m, +5m or " + time.Now().Format(m.layout.Time)
+			return m, m.task.Focus()
+		case key.Matches(msg, m.keys.EditRunning):
 			return m, func() tea.Msg {
 				return EditRunningTaskMsg{}
 			}
@@ -120,9 +339,90 @@ func (m Model) handleKeypressTaskInput(msg tea.KeyMsg) (Model, tea.Cmd) {
 	}
 }
 
+// startTyped starts the typed task. A time at the end of the input sets its
+// start, a range adds it as a finished entry instead.
+func (m Model) startTyped() (Model, tea.Cmd) {
+	now := time.Now()
+	value, span, err := m.parser.Trailing(m.task.Value(), now)
+	if err != nil {
+		return m, notify.Errorf("%v", err)
+	}
+	name, project, tags := models.ParseInput(value)
+	if project == nil {
+		project = m.selectedProject()
+	}
+	entry := &models.Entry{Start: now, Name: name, Project: project, Tags: tags}
+	if span == nil {
+		return m, func() tea.Msg {
+			return StartRunningMsg{RunningTask: entry}
+		}
+	}
+	entry.Start, entry.End = span.Start, span.End
+	if err := models.ValidateTimes(entry.Start, entry.End, now); err != nil {
+		return m, notify.Errorf("%s: %v", name, err)
+	}
+	if entry.End == nil {
+		return m, func() tea.Msg {
+			return StartRunningMsg{RunningTask: entry}
+		}
+	}
+	return m, func() tea.Msg {
+		return PastEntryMsg{Entry: entry}
+	}
+}
+
+// handleKeypressAdjust reads a new start for the running task, relative like -10m or as a time.
+func (m Model) handleKeypressAdjust(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.leaveAdjust()
+		return m, nil
+	case key.Matches(msg, m.keys.Start):
+		start, err := m.parser.Correction(m.task.Value(), m.runningTask.Start, time.Now())
+		if err != nil {
+			return m, notify.Errorf("start: %v", err)
+		}
+		m.leaveAdjust()
+		return m, func() tea.Msg {
+			return AdjustStartMsg{Start: start}
+		}
+	}
+	var cmd tea.Cmd
+	m.task, cmd = m.task.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) leaveAdjust() {
+	m.state = running
+	m.task.Reset()
+	m.task.Placeholder = placeholder
+}
+
 func (m Model) View() string {
-	if m.state == input {
-		return m.task.View()
+	switch m.state {
+	case adjusting:
+		hint := m.runningTask.Name + " started at " + m.runningTask.Start.Format(m.layout.Time)
+		return lipgloss.JoinVertical(lipgloss.Left, m.task.View(), m.theme.SubtextStyle().PaddingLeft(2).Render(hint))
+	case confirmTrim:
+		prompt := fmt.Sprintf("%s ends at %s, cut it short at %s? (%s/n)", m.trim.Previous.Name,
+			m.trim.Previous.End.Format(m.layout.Time), m.trim.Start.Format(m.layout.Time), m.keys.Confirm.Help().Key)
+		below := m
+		below.state = m.trimFrom
+		return lipgloss.JoinVertical(lipgloss.Left, m.theme.AccentStyle().Render(prompt), below.View())
+	}
+	if m.state != running {
+		project := "no project"
+		if p := m.selectedProject(); p != nil {
+			project = p.String()
+		}
+		hint := "@ " + project
+		if m.state == switching {
+			hint = "switching from " + m.runningTask.Name + " \uF444 " + hint
+		}
+		if m.pomodoroOn {
+			hint += " \uF444 pomodoro"
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, m.task.View(), m.theme.SubtextStyle().PaddingLeft(2).Render(hint))
 	} else {
 		return m.viewRunningTask()
 	}
@@ -130,14 +430,151 @@ func (m Model) View() string {
 
 func (m Model) StatusBar() string {
 	if m.state == input {
-		return "<enter> start"
+		return keys.Status(m.keys.Start, m.keys.ProjectPrev, m.keys.ProjectNext, m.keys.Pomodoro) + " \uF444 @client/project #tag in name"
+	} else if m.state == adjusting {
+		start := m.keys.Start
+		start.SetHelp(start.Help().Key, "adjust")
+		return keys.Status(start, m.keys.Cancel) + " \uF444 -10m, +5m or a time"
+	} else if m.state == confirmTrim {
+		return keys.Status(m.keys.Confirm) + " \uF444 <any> cancel"
+	} else if m.state == switching {
+		start := m.keys.Start
+		start.SetHelp(start.Help().Key, "switch")
+		return keys.Status(start, m.keys.Cancel, m.keys.ProjectPrev, m.keys.ProjectNext)
 	} else {
-		return "<space> stop \uF444 <enter> edit \uF444 <tab> list"
+		pause := m.keys.Pause
+		if m.runningTask != nil && m.runningTask.Paused() {
+			pause.SetHelp(pause.Help().Key, "resume")
+		}
+		return keys.Status(m.keys.Stop, pause, m.keys.Switch, m.keys.AdjustStart, m.keys.EditRunning, m.keys.Pomodoro)
 	}
 }
 
+// Typing reports whether keys go into the task input or answer its prompt.
+func (m Model) Typing() bool {
+	return m.state == input || m.state == switching || m.state == adjusting || m.state == confirmTrim
+}
+
 func (m Model) viewRunningTask() string {
-	elapsed := time.Since(m.runningTask.Start).Round(time.Second).String()
+	now := time.Now()
+	// pauses don't count, so the time stands still while paused
+	elapsed := m.runningTask.Duration(now).Round(time.Second).String()
+	if !m.phaseStart.IsZero() {
+		elapsed = max(m.phaseEnd(now).Sub(now), 0).Round(time.Second).String() + " left"
+		if !m.runningTask.IsBreak() {
+			elapsed += fmt.Sprintf(" \uF444 pomodoro %d", m.Pomodoros(now)+1)
+		}
+	}
+	if m.runningTask.Paused() {
+		elapsed += " \uF444 paused"
+	}
 	left := m.spinner.View() + " " + m.theme.AccentStyle().Render(m.runningTask.Name)
+	if m.runningTask.Project != nil {
+		left += " " + m.theme.SubtextStyle().Render("@"+m.runningTask.Project.String())
+	}
+	if tags := m.runningTask.TagString(); tags != "" {
+		left += " " + m.theme.SubtextStyle().Render(tags)
+	}
 	return lipgloss.JoinVertical(lipgloss.Left, left, m.theme.SubtextStyle().PaddingLeft(2).Render(elapsed))
 }
+
+func (m Model) selectedProject() *models.Project {
+	if m.project < 0 || m.project >= len(m.projects) {
+		return nil
+	}
+	return m.projects[m.project]
+}
+
+// Pomodoros returns the number of pomodoros finished on the day of now.
+func (m Model) Pomodoros(now time.Time) int {
+	if !sameDay(m.finishedOn, now) {
+		return 0
+	}
+	return m.finished
+}
+
+// PomodoroOn reports whether running tasks count down in Pomodoro intervals.
+func (m Model) PomodoroOn() bool {
+	return m.pomodoroOn
+}
+
+func (m *Model) togglePomodoro() tea.Cmd {
+	m.pomodoroOn = !m.pomodoroOn
+	if !m.pomodoroOn {
+		m.phaseStart = time.Time{}
+		return notify.Infof("pomodoro mode off")
+	}
+	if m.runningTask == nil {
+		return notify.Infof("pomodoro mode on, the next task counts down %s", m.pomodoro.Work)
+	}
+	// the interval of a task that is already running starts now
+	return tea.Batch(m.startPhase(time.Now()), notify.Infof("pomodoro mode on"))
+}
+
+// startPhase counts down the interval of the running entry that started at start.
+func (m *Model) startPhase(start time.Time) tea.Cmd {
+	m.phaseStart = start
+	m.phaseId++
+	if m.runningTask.Paused() {
+		return nil
+	}
+	return m.tick(time.Until(m.phaseEnd(time.Now())))
+}
+
+// phaseEnd returns when the running interval ends, pushed back by the pauses until now.
+func (m Model) phaseEnd(now time.Time) time.Time {
+	return m.phaseStart.Add(m.pomodoro.Length(m.runningTask) + m.runningTask.PausedBetween(m.phaseStart, now))
+}
+
+func (m Model) tick(wait time.Duration) tea.Cmd {
+	id := m.phaseId
+	return tea.Tick(max(wait, 0), func(time.Time) tea.Msg {
+		return PhaseTickMsg{id: id}
+	})
+}
+
+// endPhase follows a work interval with a break and a break with the task worked on before.
+func (m *Model) endPhase() tea.Cmd {
+	at := m.phaseEnd(time.Now())
+	m.phaseStart = time.Time{}
+	msg := PhaseEndedMsg{At: at}
+	if m.runningTask.IsBreak() {
+		msg.Text = "break is over"
+		if w := m.lastWork; w != nil {
+			msg.Next = w.ContinueAt(at)
+			msg.Text += ", back to " + w.Name
+		}
+	} else {
+		n := m.Pomodoros(at) + 1
+		msg.Next = m.pomodoro.Break(n, at)
+		msg.Text = fmt.Sprintf("pomodoro %d done, time for a %s", n, strings.ToLower(msg.Next.Name))
+	}
+	if msg.Next != nil && time.Now().After(at.Add(m.pomodoro.Length(msg.Next))) {
+		// timekeeper was not running, don't make up intervals for that time
+		msg.Next = nil
+		msg.Text = m.runningTask.Name + " ran out while timekeeper was not running, it was stopped at its end"
+	}
+	if msg.Next != nil && msg.Next.IsBreak() {
+		// a pomodoro counts once its break is recorded
+		m.countPomodoro(at)
+	}
+	m.next = msg.Next
+	return func() tea.Msg {
+		return msg
+	}
+}
+
+// countPomodoro adds a finished pomodoro to the day of at and returns the day's count.
+func (m *Model) countPomodoro(at time.Time) int {
+	if !sameDay(m.finishedOn, at) {
+		m.finished, m.finishedOn = 0, at
+	}
+	m.finished++
+	return m.finished
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,170 @@
+// Package backup writes timestamped copies of all entries and restores them.
+// Backups use the JSON export format, so they work with every store and can be
+// read by other tools as well.
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/export"
+	"github.com/danielroehrig/timekeeper/log"
+)
+
+const (
+	prefix     = "timekeeper-"
+	suffix     = ".json"
+	timeLayout = "20060102-150405.000"
+)
+
+// Options configure the scheduled backups.
+type Options struct {
+	Dir string
+	// Interval is the minimum time between two backups, 0 turns them off.
+	Interval time.Duration
+	// KeepDaily and KeepWeekly are the number of days and weeks for which the
+	// newest backup is kept. The newest backup overall is never removed.
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// File is a backup in the backup folder.
+type File struct {
+	Path string
+	Time time.Time
+}
+
+// Write saves all entries of the store to a new file in dir and returns its path.
+// The file is written under a temporary name first, so a backup is either complete or missing.
+func Write(store dbaccess.EntryStore, dir string, now time.Time) (string, error) {
+	entries, err := store.LoadEntries()
+	if err != nil {
+		return "", fmt.Errorf("could not load entries for the backup: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create backup folder: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".backup-*")
+	if err != nil {
+		return "", fmt.Errorf("could not create backup: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := (export.JSON{}).Write(tmp, entries); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("could not write backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("could not write backup: %w", err)
+	}
+	file := filepath.Join(dir, prefix+now.UTC().Format(timeLayout)+suffix)
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", fmt.Errorf("could not write backup: %w", err)
+	}
+	return file, nil
+}
+
+// List returns the backups in dir, newest first. Other files are ignored.
+func List(dir string) ([]File, error) {
+	items, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list backups: %w", err)
+	}
+	var files []File
+	for _, item := range items {
+		name := item.Name()
+		if item.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		t, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		files = append(files, File{Path: filepath.Join(dir, name), Time: t})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Time.After(files[j].Time)
+	})
+	return files, nil
+}
+
+// Prune removes the backups not kept by the retention rules and returns their paths.
+func Prune(dir string, keepDaily, keepWeekly int) ([]string, error) {
+	files, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, f := range expired(files, keepDaily, keepWeekly) {
+		if err := os.Remove(f.Path); err != nil {
+			return removed, fmt.Errorf("could not remove old backup: %w", err)
+		}
+		removed = append(removed, f.Path)
+	}
+	return removed, nil
+}
+
+// expired picks the backups to remove from files sorted newest first. The
+// newest backup of each of the last keepDaily days and keepWeekly weeks that
+// have backups is kept, as is the newest backup overall.
+func expired(files []File, keepDaily, keepWeekly int) []File {
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, f := range files {
+		local := f.Time.Local()
+		day := local.Format("2006-01-02")
+		year, week := local.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%d", year, week)
+		if i == 0 {
+			keep[f.Path] = true
+		}
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[f.Path] = true
+		}
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep[f.Path] = true
+		}
+	}
+	var old []File
+	for _, f := range files {
+		if !keep[f.Path] {
+			old = append(old, f)
+		}
+	}
+	return old
+}
+
+// Scheduled writes a backup if the newest one is older than the interval and
+// prunes old ones afterward. It returns the new backup's path, empty if none was due.
+func Scheduled(store dbaccess.EntryStore, o Options, now time.Time) (string, error) {
+	if o.Interval <= 0 {
+		return "", nil
+	}
+	files, err := List(o.Dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) > 0 && now.Sub(files[0].Time) < o.Interval {
+		return "", nil
+	}
+	file, err := Write(store, o.Dir, now)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("wrote backup %s", file)
+	removed, err := Prune(o.Dir, o.KeepDaily, o.KeepWeekly)
+	for _, r := range removed {
+		log.Infof("removed old backup %s", r)
+	}
+	return file, err
+}
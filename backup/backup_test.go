@@ -0,0 +1,73 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func TestExpired(t *testing.T) {
+	// days in March 2024, the 18th is a Monday
+	file := func(day, hour int) File {
+		tm := time.Date(2024, time.March, day, hour, 0, 0, 0, time.Local)
+		return File{Path: tm.Format("02-15"), Time: tm}
+	}
+	tests := []struct {
+		name                  string
+		files                 []File
+		keepDaily, keepWeekly int
+		want                  []string
+	}{
+		{"newest of each day", []File{file(18, 18), file(18, 12), file(17, 12), file(16, 12)}, 2, 0, []string{"18-12", "16-12"}},
+		{"newest overall stays", []File{file(18, 18), file(17, 12)}, 0, 0, []string{"17-12"}},
+		{"days without backups don't count", []File{file(18, 12), file(10, 12), file(1, 12)}, 2, 0, []string{"01-12"}},
+		{"newest of each week", []File{file(18, 12), file(15, 12), file(14, 12), file(4, 12)}, 1, 2, []string{"14-12", "04-12"}},
+		{"days and weeks together", []File{file(18, 12), file(17, 12), file(15, 12), file(4, 12)}, 2, 3, []string{"15-12"}},
+		{"nothing to keep beyond", []File{file(18, 12)}, 7, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range expired(tt.files, tt.keepDaily, tt.keepWeekly) {
+				got = append(got, f.Path)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expired = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expired = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	store := openStore(t, &models.Entry{Name: "review", Start: at(1, 9, 0), End: ptr(at(1, 10, 0))})
+	var written []string
+	for _, day := range []int{18, 17, 16} {
+		file, err := Write(store, dir, time.Date(2024, time.March, day, 12, 0, 0, 0, time.Local))
+		if err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, file)
+	}
+	removed, err := Prune(dir, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != written[2] {
+		t.Errorf("Prune removed %v, want %s", removed, written[2])
+	}
+	files, err := List(dir)
+	if err != nil || len(files) != 2 || files[0].Path != written[0] {
+		t.Errorf("List after pruning = %v, %v", files, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*")); len(matches) != 2 {
+		t.Errorf("%d files left in the backup folder, want 2", len(matches))
+	}
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/export"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Read loads and validates a backup. Invalid entries are left out and listed
+// in the error, the valid ones are returned along with it. Entries are nil if
+// the file can't be read at all.
+func Read(file string) ([]*models.Entry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open backup: %w", err)
+	}
+	defer f.Close()
+	var doc export.Document
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s is not a timekeeper backup: %w", file, err)
+	}
+	// fields may be added within a version, only the version tells a backup apart
+	if doc.Version == 0 {
+		return nil, fmt.Errorf("%s is not a timekeeper backup, it has no version", file)
+	}
+	if doc.Version > export.SchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, supported is up to %d", file, doc.Version, export.SchemaVersion)
+	}
+
+	// only the latest running entry can be restored
+	running := -1
+	for i, e := range doc.Entries {
+		if e.End == nil && (running < 0 || e.Start.After(doc.Entries[running].Start)) {
+			running = i
+		}
+	}
+	var errs []error
+	ids := make(map[string]bool, len(doc.Entries))
+	entries := make([]*models.Entry, 0, len(doc.Entries))
+	for i, e := range doc.Entries {
+		name := fmt.Sprintf("entry %d", i+1)
+		if e.Id != "" {
+			name += " (" + e.Id + ")"
+		}
+		var problem string
+		switch {
+		case e.Id != "" && ids[e.Id]:
+			problem = "duplicate id"
+		case e.Start.IsZero():
+			problem = "missing start"
+		case e.End == nil && i != running:
+			problem = "another entry is running"
+		case e.End != nil && e.End.Before(e.Start):
+			problem = "ends before it starts"
+		}
+		if problem != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", name, problem))
+			continue
+		}
+		ids[e.Id] = true
+		entries = append(entries, fromExport(e))
+	}
+	if len(errs) > 0 {
+		return entries, fmt.Errorf("%s has %d invalid entries: %w", file, len(errs), errors.Join(errs...))
+	}
+	return entries, nil
+}
+
+func fromExport(e export.Entry) *models.Entry {
+	var project *models.Project
+	if e.Project != "" {
+		project = &models.Project{Name: e.Project}
+		if e.Client != "" {
+			project.Client = &models.Client{Name: e.Client}
+		}
+	}
+	var pauses []models.Pause
+	for _, p := range e.Pauses {
+		pauses = append(pauses, models.Pause{Start: p.Start, End: p.End})
+	}
+	return &models.Entry{
+		ObjectId: e.Id,
+		Name:     e.Name,
+		Start:    e.Start,
+		End:      e.End,
+		Content:  e.Content,
+		Project:  project,
+		Tags:     models.NormalizeTags(e.Tags),
+		Pauses:   pauses,
+	}
+}
+
+// Result tells what a restore changed.
+type Result struct {
+	Added   int
+	Removed int
+	Skipped int // entries of a merge that are already in the store
+}
+
+// Replace deletes all entries of the store and adds the restored ones.
+// Stores that need ids of their own assign new ones. This is no transaction:
+// if a delete or add fails, the store is left with only part of the entries
+// and the Result tells how far it got. A backup written before, like the one
+// the restore command saves, is the only way back.
+func Replace(store dbaccess.EntryStore, entries []*models.Entry) (Result, error) {
+	var r Result
+	existing, err := store.LoadEntries()
+	if err != nil {
+		return r, fmt.Errorf("could not load entries: %w", err)
+	}
+	for _, e := range existing {
+		if err := store.DeleteEntry(e); err != nil {
+			return r, fmt.Errorf("could not delete entry %s: %w", e.ObjectId, err)
+		}
+		r.Removed++
+	}
+	return add(store, entries, r)
+}
+
+// Merge adds the restored entries missing in the store. Entries count as
+// present when their id or their start, end and name to the second match.
+// A running entry is only added if no other task is running.
+func Merge(store dbaccess.EntryStore, entries []*models.Entry) (Result, error) {
+	var r Result
+	existing, err := store.LoadEntries()
+	if err != nil {
+		return r, fmt.Errorf("could not load entries: %w", err)
+	}
+	seen := make(map[string]bool, 2*len(existing))
+	running := false
+	for _, e := range existing {
+		seen[e.ObjectId] = true
+		seen[key(e)] = true
+		running = running || e.End == nil
+	}
+	missing := make([]*models.Entry, 0, len(entries))
+	for _, e := range entries {
+		if (e.ObjectId != "" && seen[e.ObjectId]) || seen[key(e)] || (e.End == nil && running) {
+			r.Skipped++
+			continue
+		}
+		missing = append(missing, e)
+	}
+	return add(store, missing, r)
+}
+
+func add(store dbaccess.EntryStore, entries []*models.Entry, r Result) (Result, error) {
+	for _, e := range entries {
+		if err := store.AddEntry(e); err != nil {
+			return r, fmt.Errorf("could not add entry %q from %s: %w", e.Name, e.Start.Format(time.RFC3339), err)
+		}
+		r.Added++
+	}
+	return r, nil
+}
+
+func key(e *models.Entry) string {
+	end := ""
+	if e.End != nil {
+		end = e.End.UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+	return e.Start.UTC().Truncate(time.Second).Format(time.RFC3339) + "|" + end + "|" + e.Name
+}
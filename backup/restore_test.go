@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func openStore(t *testing.T, entries ...*models.Entry) dbaccess.EntryStore {
+	t.Helper()
+	store, err := dbaccess.Open(dbaccess.SQLite, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, e := range entries {
+		if err := store.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// failingStore fails adding entries once it added the given number.
+type failingStore struct {
+	dbaccess.EntryStore
+	adds int
+}
+
+func (s *failingStore) AddEntry(e *models.Entry) error {
+	if s.adds == 0 {
+		return errors.New("disk full")
+	}
+	s.adds--
+	return s.EntryStore.AddEntry(e)
+}
+
+func TestReplaceFailingHalfway(t *testing.T) {
+	store := openStore(t,
+		&models.Entry{Name: "review", Start: at(1, 9, 0), End: ptr(at(1, 10, 0))},
+		&models.Entry{Name: "planning", Start: at(1, 10, 0), End: ptr(at(1, 11, 0))},
+	)
+	safety, err := Write(store, t.TempDir(), at(2, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := []*models.Entry{
+		{Name: "a", Start: at(5, 9, 0), End: ptr(at(5, 10, 0))},
+		{Name: "b", Start: at(5, 10, 0), End: ptr(at(5, 11, 0))},
+		{Name: "c", Start: at(5, 11, 0), End: ptr(at(5, 12, 0))},
+	}
+	r, err := Replace(&failingStore{EntryStore: store, adds: 1}, restored)
+	if err == nil {
+		t.Fatal("Replace succeeded although adding failed")
+	}
+	if r.Removed != 2 || r.Added != 1 {
+		t.Errorf("Replace removed %d and added %d, want 2 and 1", r.Removed, r.Added)
+	}
+	if entries, _ := store.LoadEntries(); len(entries) != 1 {
+		t.Errorf("%d entries left after the failed restore, want 1", len(entries))
+	}
+
+	// the backup written before brings the previous entries back
+	previous, err := Read(safety)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Replace(store, previous); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.LoadEntries()
+	if err != nil || len(entries) != 2 || entries[0].Name != "planning" || entries[1].Name != "review" {
+		t.Errorf("after restoring the safety backup LoadEntries = %v, %v", entries, err)
+	}
+}
+
+func TestMergeSkipsPresentEntries(t *testing.T) {
+	present := &models.Entry{Name: "review", Start: at(1, 9, 0), End: ptr(at(1, 10, 0))}
+	tests := []struct {
+		name    string
+		sameId  bool // the restored entry has the id of the present one
+		running bool // a task runs in the store
+		entry   *models.Entry
+		added   bool
+	}{
+		{"same id", true, false, &models.Entry{Name: "renamed", Start: at(2, 9, 0), End: ptr(at(2, 10, 0))}, false},
+		{"same start, end and name", false, false, &models.Entry{Name: "review", Start: at(1, 9, 0), End: ptr(at(1, 10, 0))}, false},
+		{"differs below a second", false, false, &models.Entry{Name: "review", Start: at(1, 9, 0).Add(300 * time.Millisecond), End: ptr(at(1, 10, 0))}, false},
+		{"other name", false, false, &models.Entry{Name: "planning", Start: at(1, 9, 0), End: ptr(at(1, 10, 0))}, true},
+		{"other end", false, false, &models.Entry{Name: "review", Start: at(1, 9, 0), End: ptr(at(1, 10, 1))}, true},
+		{"running", false, false, &models.Entry{Name: "review", Start: at(3, 9, 0)}, true},
+		{"running while another runs", false, true, &models.Entry{Name: "review", Start: at(3, 9, 0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := *present
+			store := openStore(t, &p)
+			if tt.running {
+				if err := store.AddEntry(&models.Entry{Name: "standup", Start: at(3, 8, 0)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.sameId {
+				tt.entry.ObjectId = p.ObjectId
+			}
+			r, err := Merge(store, []*models.Entry{tt.entry})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added := r.Added == 1; added != tt.added || r.Added+r.Skipped != 1 {
+				t.Errorf("Merge added %d and skipped %d, want the entry added: %v", r.Added, r.Skipped, tt.added)
+			}
+		})
+	}
+}
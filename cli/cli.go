@@ -0,0 +1,450 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/config"
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/export"
+	"github.com/danielroehrig/timekeeper/importer"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Exit codes returned by Run. Scripts can rely on them.
+const (
+	ExitOK = iota
+	ExitError
+	ExitUsage
+	ExitNotRunning
+	ExitAlreadyRunning
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(c *context, args []string) int
+	// standalone commands open the stores they need themselves
+	standalone bool
+}
+
+type context struct {
+	db     dbaccess.EntryStore
+	cfg    config.Config
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var commands = map[string]command{
+	"start":   {usage: "start [--json] [--project client/project] [--at when] <name> [#tag...]", help: "start tracking a new task, --at takes a time like 9am or 10m ago", run: runStart},
+	"stop":    {usage: "stop [--json] [--at when]", help: "stop the running task, --at takes a time like 17:30 or 5m ago", run: runStop},
+	"status":  {usage: "status [--json]", help: "show the running task", run: runStatus},
+	"ls":      {usage: "ls [--json] [--since when]", help: "list entries, --since takes a day, a time or a duration like 72h", run: runList},
+	"export":  {usage: "export [--format f] [--from] [--to] [--project] [--tag] [-o file]", help: "export finished entries as csv, json or ics", run: runExport},
+	"import":  {usage: "import [--format f] [--preset p] [--map m] [--tz zone] [--dry-run] <file>...", help: "import entries from csv, json or timewarrior data files", run: runImport},
+	"restore": {usage: "restore [--merge] [--skip-invalid] <file>", help: "replace all entries with a backup, --merge only adds missing ones", run: runRestore},
+	"migrate": {usage: "migrate [--from store] [--to store]", help: "copy all entries from one store to another, clover to sqlite by default", run: runMigrate, standalone: true},
+	"doctor":  {usage: "doctor [-i] [--fix] [--close-at when]", help: "check entries for overlaps and other anomalies, -i asks for each repair, --fix applies all", run: runDoctor},
+}
+
+var order = []string{"start", "stop", "status", "ls", "export", "import", "restore", "migrate", "doctor"}
+
+// NeedsDatabase reports whether the subcommand has to open the store.
+// Help and unknown commands are answered without touching it.
+func NeedsDatabase(name string) bool {
+	cmd, ok := commands[name]
+	return ok && !cmd.standalone
+}
+
+// Run executes the subcommand in args[0] and returns the process exit code.
+func Run(db dbaccess.EntryStore, cfg config.Config, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		Usage(stderr)
+		return ExitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		Usage(stderr)
+		switch args[0] {
+		case "help", "-h", "--help":
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	return cmd.run(&context{db: db, cfg: cfg, stdin: stdin, stdout: stdout, stderr: stderr}, args[1:])
+}
+
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: timekeeper [command]")
+	fmt.Fprintln(w, "\nwithout a command the interactive UI is started\n\ncommands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range order {
+		fmt.Fprintf(tw, "  %s\t%s\n", commands[name].usage, commands[name].help)
+	}
+	tw.Flush()
+}
+
+func runStart(c *context, args []string) int {
+	fs, asJSON := c.flagSet("start")
+	project := fs.String("project", "", "project of the task, written as client/project or project")
+	at := fs.String("at", "", "start time like 9am, 10m ago or yesterday 14:00 (default now)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	start, err := c.parseAt(*at, nil)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "start: --at: %v\n", err)
+		return ExitUsage
+	}
+	name, inputProject, tags := models.ParseInput(strings.Join(fs.Args(), " "))
+	if *project != "" {
+		inputProject = models.ParseProject(*project)
+	}
+	if name == "" {
+		fmt.Fprintln(c.stderr, "start: missing task name")
+		return ExitUsage
+	}
+	running, err := c.db.GetRunning()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "start: %v\n", err)
+		return ExitError
+	}
+	if running != nil {
+		fmt.Fprintf(c.stderr, "start: %q is already running\n", running.Name)
+		return ExitAlreadyRunning
+	}
+	e := &models.Entry{
+		Start:   start,
+		Name:    name,
+		Project: inputProject,
+		Tags:    tags,
+	}
+	// a backdated start may reach into the entries before it
+	entries, err := c.db.LoadEntriesBetween(time.Time{}, time.Time{})
+	if err != nil {
+		fmt.Fprintf(c.stderr, "start: %v\n", err)
+		return ExitError
+	}
+	previous, err := models.Overlap(entries, e, start, nil, time.Now())
+	if err != nil {
+		fmt.Fprintf(c.stderr, "start: --at: %v\n", err)
+		return ExitUsage
+	}
+	if previous != nil {
+		previous.End = &start
+		if err := c.db.UpdateEntry(previous); err != nil {
+			fmt.Fprintf(c.stderr, "start: %v\n", err)
+			return ExitError
+		}
+		fmt.Fprintf(c.stderr, "start: cut %q short to end at %s\n", previous.Name, c.formatTime(start))
+	}
+	if err := c.db.AddEntry(e); err != nil {
+		fmt.Fprintf(c.stderr, "start: %v\n", err)
+		return ExitError
+	}
+	return c.writeEntries(*asJSON, e)
+}
+
+func runStop(c *context, args []string) int {
+	fs, asJSON := c.flagSet("stop")
+	at := fs.String("at", "", "end time like 17:30 or 5m ago (default now)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	running, err := c.db.GetRunning()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "stop: %v\n", err)
+		return ExitError
+	}
+	if running == nil {
+		fmt.Fprintln(c.stderr, "stop: no task is running")
+		return ExitNotRunning
+	}
+	end, err := c.parseAt(*at, running)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "stop: --at: %v\n", err)
+		return ExitUsage
+	}
+	running.Stop(end)
+	if err := c.db.UpdateEntry(running); err != nil {
+		fmt.Fprintf(c.stderr, "stop: %v\n", err)
+		return ExitError
+	}
+	return c.writeEntries(*asJSON, running)
+}
+
+func runStatus(c *context, args []string) int {
+	fs, asJSON := c.flagSet("status")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	running, err := c.db.GetRunning()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "status: %v\n", err)
+		return ExitError
+	}
+	if running == nil {
+		return ExitNotRunning
+	}
+	return c.writeEntries(*asJSON, running)
+}
+
+func runList(c *context, args []string) int {
+	fs, asJSON := c.flagSet("ls")
+	since := fs.String("since", "", "only list entries started after this date, time or duration ago (default today)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	from, err := c.parseSince(*since, time.Now())
+	if err != nil {
+		fmt.Fprintf(c.stderr, "ls: %v\n", err)
+		return ExitUsage
+	}
+	entries, err := c.db.LoadEntriesBetween(from, time.Time{})
+	if err != nil {
+		fmt.Fprintf(c.stderr, "ls: %v\n", err)
+		return ExitError
+	}
+	return c.writeEntries(*asJSON, entries...)
+}
+
+func runExport(c *context, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	format := fs.String("format", "", "one of "+strings.Join(export.Formats(), ", ")+", derived from the output file if empty")
+	from := fs.String("from", "", "only export entries started at or after this date, time or duration ago")
+	to := fs.String("to", "", "only export entries started before this date, time or duration ago")
+	project := fs.String("project", "", "only export entries of this project, written as project or client/project")
+	output := fs.String("o", "-", "output file, - for stdout")
+	var tags tagList
+	fs.Var(&tags, "tag", "only export entries with this tag, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	now := time.Now()
+	opts := export.Options{
+		Project: *project,
+		Tags:    tags,
+		Format:  *format,
+		Path:    *output,
+		Out:     c.stdout,
+	}
+	var err error
+	if *from != "" {
+		if opts.From, err = c.parseSince(*from, now); err != nil {
+			fmt.Fprintf(c.stderr, "export: %v\n", err)
+			return ExitUsage
+		}
+	}
+	if *to != "" {
+		if opts.To, err = c.parseSince(*to, now); err != nil {
+			fmt.Fprintf(c.stderr, "export: %v\n", err)
+			return ExitUsage
+		}
+	}
+	if opts.FormatFor() == "" {
+		opts.Format = "csv"
+	}
+	if _, ok := export.Lookup(opts.FormatFor()); !ok {
+		fmt.Fprintf(c.stderr, "export: unknown format %q, use one of %s\n", opts.FormatFor(), strings.Join(export.Formats(), ", "))
+		return ExitUsage
+	}
+	entries, err := c.db.LoadEntriesBetween(opts.From, opts.To)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "export: %v\n", err)
+		return ExitError
+	}
+	n, err := export.Export(entries, opts)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "export: %v\n", err)
+		return ExitError
+	}
+	if opts.Path != "-" {
+		fmt.Fprintf(c.stderr, "exported %d entries to %s\n", n, opts.Path)
+	}
+	return ExitOK
+}
+
+func runImport(c *context, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	format := fs.String("format", "", "one of "+strings.Join(importer.Formats(), ", ")+", derived from the file extension if empty")
+	preset := fs.String("preset", "", "csv column preset, one of timekeeper, toggl, clockify, detected from the header if empty")
+	mapping := fs.String("map", "", "csv column mapping like name=Description,start=Start,end=End")
+	tz := fs.String("tz", "Local", "time zone for times without an offset")
+	dryRun := fs.Bool("dry-run", false, "only show what would be imported")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(c.stderr, "import: missing file")
+		return ExitUsage
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "import: %v\n", err)
+		return ExitUsage
+	}
+	var csvMapping importer.Mapping
+	switch {
+	case *mapping != "":
+		if csvMapping, err = importer.ParseMapping(*mapping); err != nil {
+			fmt.Fprintf(c.stderr, "import: %v\n", err)
+			return ExitUsage
+		}
+	case *preset != "":
+		var ok bool
+		if csvMapping, ok = importer.Presets[strings.ToLower(*preset)]; !ok {
+			fmt.Fprintf(c.stderr, "import: unknown preset %q\n", *preset)
+			return ExitUsage
+		}
+	}
+
+	existing, err := c.db.LoadEntriesBetween(time.Time{}, time.Time{})
+	if err != nil {
+		fmt.Fprintf(c.stderr, "import: %v\n", err)
+		return ExitError
+	}
+	code := ExitOK
+	imported, rejected := 0, 0
+	for _, file := range fs.Args() {
+		reader, err := importReader(file, *format, csvMapping)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "import: %v\n", err)
+			return ExitUsage
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "import: %v\n", err)
+			return ExitError
+		}
+		records, rejections, err := reader.Read(f, loc)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(c.stderr, "import: %s: %v\n", file, err)
+			return ExitError
+		}
+		result := importer.Plan(existing, records, rejections)
+		for _, r := range result.Accepted {
+			if !*dryRun {
+				if err := c.db.AddEntry(r.Entry); err != nil {
+					fmt.Fprintf(c.stderr, "%s:%d: %v\n", file, r.Line, err)
+					code = ExitError
+					continue
+				}
+			}
+			existing = append(existing, r.Entry)
+			imported++
+			c.writeEntries(false, r.Entry)
+		}
+		for _, r := range result.Rejected {
+			fmt.Fprintf(c.stderr, "%s:%d: %s\n", file, r.Line, r.Reason)
+		}
+		rejected += len(result.Rejected)
+	}
+	verb := "imported"
+	if *dryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(c.stderr, "%s %d entries, rejected %d\n", verb, imported, rejected)
+	return code
+}
+
+func importReader(file, format string, mapping importer.Mapping) (importer.Reader, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			format = "json"
+		case ".data":
+			format = "timewarrior"
+		default:
+			format = "csv"
+		}
+	}
+	if format == "csv" && mapping != nil {
+		return importer.CSV{Mapping: mapping}, nil
+	}
+	r, ok := importer.Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(importer.Formats(), ", "))
+	}
+	return r, nil
+}
+
+type tagList []string
+
+func (t *tagList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagList) Set(v string) error {
+	*t = append(*t, v)
+	return nil
+}
+
+func (c *context) flagSet(name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	asJSON := fs.Bool("json", false, "print entries as JSON")
+	return fs, asJSON
+}
+
+// parseSince reads a day, a time or a duration ago, empty means today.
+func (c *context) parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		s = "today"
+	}
+	return c.cfg.TimeParser().Day(s, now)
+}
+
+// parseAt reads the time given to --at, empty means now. Times without a day
+// are today. With running the time has to end it, otherwise it starts a task.
+func (c *context) parseAt(s string, running *models.Entry) (time.Time, error) {
+	now := time.Now()
+	if s == "" {
+		return now, nil
+	}
+	t, err := c.cfg.TimeParser().Time(s, now, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if running != nil {
+		return t, models.ValidateTimes(running.Start, &t, now)
+	}
+	return t, models.ValidateTimes(t, nil, now)
+}
+
+// writeEntries prints one tab separated line per entry:
+// id, start, end (or "-" while running), duration in seconds, name.
+// As JSON it prints an array of entries in the export schema.
+func (c *context) writeEntries(asJSON bool, entries ...*models.Entry) int {
+	now := time.Now()
+	if asJSON {
+		out := make([]export.Entry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, export.NewEntry(e, now))
+		}
+		if err := json.NewEncoder(c.stdout).Encode(out); err != nil {
+			fmt.Fprintf(c.stderr, "could not encode entries: %v\n", err)
+			return ExitError
+		}
+		return ExitOK
+	}
+	for _, e := range entries {
+		end := "-"
+		if e.End != nil {
+			end = e.End.Format(time.RFC3339)
+		}
+		fmt.Fprintf(c.stdout, "%s\t%s\t%s\t%d\t%s\n",
+			e.ObjectId, e.Start.Format(time.RFC3339), end, int64(e.Duration(now).Seconds()), e.Name)
+	}
+	return ExitOK
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/config"
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// newStore returns a store in a temp dir and a config using it.
+func newStore(t *testing.T, entries ...*models.Entry) (dbaccess.EntryStore, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TIMEKEEPER_CONFIG", filepath.Join(dir, "config.yml"))
+	t.Setenv("TIMEKEEPER_DATABASE_PATH", dir)
+	t.Setenv("TIMEKEEPER_LOG_FILE", filepath.Join(dir, "log"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := dbaccess.Open(dbaccess.SQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, e := range entries {
+		if err := db.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, cfg
+}
+
+func run(db dbaccess.EntryStore, cfg config.Config, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := Run(db, cfg, args, strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestExportToStdout(t *testing.T) {
+	start := time.Date(2024, time.March, 12, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	db, cfg := newStore(t,
+		&models.Entry{Name: "review", Start: start, End: &end},
+		&models.Entry{Name: "running", Start: end},
+	)
+	stdout, stderr, code := run(db, cfg, "export", "--format", "csv")
+	if code != ExitOK {
+		t.Fatalf("export exited with %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "review") {
+		t.Errorf("export wrote %q, want the header and review", stdout)
+	}
+}
+
+func TestStartAtOverlap(t *testing.T) {
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	end := start.Add(2 * time.Hour)
+	tests := []struct {
+		name    string
+		at      string
+		code    int
+		trimmed bool
+	}{
+		{"after the previous entry", "30m ago", ExitOK, false},
+		{"into the end of the previous entry", "90m ago", ExitOK, true},
+		{"before the previous entry", "4h ago", ExitUsage, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := &models.Entry{Name: "review", Start: start, End: &end}
+			db, cfg := newStore(t, previous)
+			_, stderr, code := run(db, cfg, "start", "--at", tt.at, "planning")
+			if code != tt.code {
+				t.Fatalf("start exited with %d, want %d: %s", code, tt.code, stderr)
+			}
+			entries, err := db.LoadEntries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[bool]int{true: 2, false: 1}[code == ExitOK]; len(entries) != want {
+				t.Fatalf("%d entries stored, want %d", len(entries), want)
+			}
+			got := entries[len(entries)-1]
+			if got.Name != "review" {
+				t.Fatalf("oldest entry is %s, want review", got.Name)
+			}
+			if trimmed := got.End.Before(end); trimmed != tt.trimmed {
+				t.Errorf("review ends at %s, trimmed %v, want %v", got.End, trimmed, tt.trimmed)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/backup"
+	"github.com/danielroehrig/timekeeper/doctor"
+)
+
+// runDoctor lists the problems of all entries, one tab separated
+// `kind id detail` line each. With -i or --fix they are repaired where
+// possible and the store is checked again.
+func runDoctor(c *context, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	interactive := fs.Bool("i", false, "ask before each repair")
+	fix := fs.Bool("fix", false, "apply all repairs without asking")
+	closeAt := fs.String("close-at", "", "end of orphaned running entries like 18:00 or yesterday 18:00 (default where the next entry starts)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() > 0 || *interactive && *fix {
+		fmt.Fprintln(c.stderr, "doctor: expecting either -i or --fix and no arguments")
+		return ExitUsage
+	}
+	parser := c.cfg.TimeParser()
+	now := time.Now()
+	var at time.Time
+	if *closeAt != "" {
+		var err error
+		if at, err = parser.Time(*closeAt, now, now); err != nil {
+			fmt.Fprintf(c.stderr, "doctor: --close-at: %v\n", err)
+			return ExitUsage
+		}
+	}
+
+	entries, err := c.db.LoadEntries()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "doctor: %v\n", err)
+		return ExitError
+	}
+	problems := doctor.Check(entries, now)
+	for _, p := range problems {
+		fmt.Fprintf(c.stdout, "%s\t%s\t%s\n", p.Kind, p.Entry.ObjectId, p.Detail())
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(c.stderr, "no problems found")
+		return ExitOK
+	}
+	if !*interactive && !*fix {
+		fmt.Fprintf(c.stderr, "%d problems found, repair them with -i or --fix\n", len(problems))
+		return ExitError
+	}
+
+	// like a restore, a repair gone wrong can be undone
+	safety, err := backup.Write(c.db, c.cfg.BackupOptions().Dir, now)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "doctor: not repairing, %v\n", err)
+		return ExitError
+	}
+	fmt.Fprintf(c.stderr, "saved the current entries to %s\n", safety)
+
+	in := bufio.NewScanner(c.stdin)
+	deleted := make(map[string]bool)
+	fixed := 0
+	for _, p := range problems {
+		repair := p.Repair()
+		if repair == doctor.Manual || deleted[p.Entry.ObjectId] || p.Other != nil && deleted[p.Other.ObjectId] {
+			continue
+		}
+		var end time.Time
+		if repair != doctor.Delete {
+			end = p.End()
+		}
+		if repair == doctor.Close && !at.IsZero() {
+			end = at
+		}
+		if *interactive {
+			var ok bool
+			if end, ok = c.askRepair(in, p, end); !ok {
+				continue
+			}
+		}
+		if err := doctor.Fix(c.db, p, end); err != nil {
+			fmt.Fprintf(c.stderr, "doctor: %s: %v\n", p.Entry.ObjectId, err)
+			continue
+		}
+		fixed++
+		switch repair {
+		case doctor.Delete:
+			deleted[p.Entry.ObjectId] = true
+			fmt.Fprintf(c.stderr, "deleted %s\n", p.Entry.ObjectId)
+		case doctor.Close:
+			fmt.Fprintf(c.stderr, "closed %s at %s\n", p.Entry.ObjectId, c.formatTime(end))
+		case doctor.Trim:
+			fmt.Fprintf(c.stderr, "trimmed %s to end at %s\n", p.Entry.ObjectId, c.formatTime(end))
+		}
+	}
+
+	// a repair can settle or uncover other problems, closing an entry late may make it overlap
+	entries, err = c.db.LoadEntries()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "doctor: %v\n", err)
+		return ExitError
+	}
+	left := doctor.Check(entries, time.Now())
+	fmt.Fprintf(c.stderr, "repaired %d problems, %d left\n", fixed, len(left))
+	if len(left) > 0 {
+		return ExitError
+	}
+	return ExitOK
+}
+
+// askRepair asks whether to repair p. Orphaned entries take the time to close
+// them at, end is the default. It returns false if the repair is skipped.
+func (c *context) askRepair(in *bufio.Scanner, p doctor.Problem, end time.Time) (time.Time, bool) {
+	id := p.Entry.ObjectId
+	for {
+		switch p.Repair() {
+		case doctor.Delete:
+			fmt.Fprintf(c.stderr, "delete %s? [y/N] ", id)
+		case doctor.Trim:
+			fmt.Fprintf(c.stderr, "trim %s %q to end at %s? [y/N] ", id, p.Entry.Name, c.formatTime(end))
+		case doctor.Close:
+			fmt.Fprintf(c.stderr, "close %s %q at [%s], n skips: ", id, p.Entry.Name, c.formatTime(end))
+		}
+		if !in.Scan() {
+			fmt.Fprintln(c.stderr)
+			return end, false
+		}
+		answer := strings.TrimSpace(in.Text())
+		if p.Repair() != doctor.Close {
+			return end, strings.EqualFold(answer, "y")
+		}
+		switch {
+		case answer == "":
+			return end, true
+		case strings.EqualFold(answer, "n"):
+			return end, false
+		}
+		// a time without a day is on the day the entry started
+		t, err := c.cfg.TimeParser().Time(answer, p.Entry.Start, time.Now())
+		if err == nil {
+			return t, true
+		}
+		fmt.Fprintf(c.stderr, "%v\n", err)
+	}
+}
+
+func (c *context) formatTime(t time.Time) string {
+	return c.cfg.TimeParser().In(t).Format(c.cfg.Layout().DateTime())
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// runMigrate copies every entry into an empty store. Entry ids are kept where the
+// target allows it, projects and clients are recreated by name.
+func runMigrate(c *context, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	from := fs.String("from", dbaccess.Clover, "store to read, clover or sqlite")
+	to := fs.String("to", dbaccess.SQLite, "store to write, clover or sqlite")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *from == *to {
+		fmt.Fprintln(c.stderr, "migrate: source and target are the same store")
+		return ExitUsage
+	}
+	source, err := dbaccess.Open(*from, c.cfg.DatabasePath)
+	if errors.As(err, new(*dbaccess.MigrationError)) {
+		fmt.Fprintf(c.stderr, "migrate: warning: %v\n", err)
+	} else if err != nil {
+		fmt.Fprintf(c.stderr, "migrate: %v\n", err)
+		return ExitError
+	}
+	defer source.Close()
+	target, err := dbaccess.Open(*to, c.cfg.DatabasePath)
+	if errors.As(err, new(*dbaccess.MigrationError)) {
+		fmt.Fprintf(c.stderr, "migrate: warning: %v\n", err)
+	} else if err != nil {
+		fmt.Fprintf(c.stderr, "migrate: %v\n", err)
+		return ExitError
+	}
+	defer target.Close()
+
+	existing, err := target.LoadEntries()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "migrate: %v\n", err)
+		return ExitError
+	}
+	if len(existing) > 0 {
+		fmt.Fprintf(c.stderr, "migrate: the %s store already has %d entries, use import to merge data\n", *to, len(existing))
+		return ExitError
+	}
+	entries, err := source.LoadEntries()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "migrate: %v\n", err)
+		return ExitError
+	}
+	// oldest first, so the target's insertion order matches the timeline
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		e.Project = withoutIds(e.Project)
+		if err := target.AddEntry(e); err != nil {
+			fmt.Fprintf(c.stderr, "migrate: entry %s: %v\n", e.ObjectId, err)
+			return ExitError
+		}
+	}
+	fmt.Fprintf(c.stderr, "migrated %d entries from %s to %s, set store: %s in the config to use it\n", len(entries), *from, *to, *to)
+	return ExitOK
+}
+
+// withoutIds copies a project without the ids of the source store.
+func withoutIds(p *models.Project) *models.Project {
+	if p == nil {
+		return nil
+	}
+	cp := &models.Project{Name: p.Name}
+	if p.Client != nil {
+		cp.Client = &models.Client{Name: p.Client.Name}
+	}
+	return cp
+}
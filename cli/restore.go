@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/backup"
+)
+
+func runRestore(c *context, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	merge := fs.Bool("merge", false, "only add the entries missing in the store instead of replacing all")
+	skipInvalid := fs.Bool("skip-invalid", false, "restore the valid entries of a backup with invalid ones")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(c.stderr, "restore: expecting exactly one backup file")
+		return ExitUsage
+	}
+	entries, err := backup.Read(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "restore: %v\n", err)
+		if entries == nil || !*skipInvalid {
+			return ExitError
+		}
+	}
+
+	// the current state is kept, a restore from the wrong file can be undone
+	safety, err := backup.Write(c.db, c.cfg.BackupOptions().Dir, time.Now())
+	if err != nil {
+		fmt.Fprintf(c.stderr, "restore: not restoring, %v\n", err)
+		return ExitError
+	}
+	fmt.Fprintf(c.stderr, "saved the current entries to %s\n", safety)
+
+	var result backup.Result
+	if *merge {
+		result, err = backup.Merge(c.db, entries)
+	} else {
+		result, err = backup.Replace(c.db, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "restore: %v, restore %s to get the previous state back\n", err, safety)
+		return ExitError
+	}
+	fmt.Fprintf(c.stderr, "restored %d entries, removed %d, skipped %d already present\n", result.Added, result.Removed, result.Skipped)
+	return ExitOK
+}
@@ -0,0 +1,380 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/app/keys"
+	"github.com/danielroehrig/timekeeper/backup"
+	"github.com/danielroehrig/timekeeper/log"
+	"github.com/danielroehrig/timekeeper/models"
+	"github.com/danielroehrig/timekeeper/themes"
+	"github.com/danielroehrig/timekeeper/timeexpr"
+	"github.com/spf13/viper"
+)
+
+// Config is read from config.yml in the user's config dir. Every key can be
+// overridden with a TIMEKEEPER_ prefixed environment variable, nested keys are
+// joined with an underscore, e.g. TIMEKEEPER_ROUNDING_INTERVAL=15m.
+type Config struct {
+	DatabasePath  string        `mapstructure:"database_path"`
+	Store         string        `mapstructure:"store"`
+	LogFile       string        `mapstructure:"log_file"`
+	LogLevel      string        `mapstructure:"log_level"`
+	LogFormat     string        `mapstructure:"log_format"`
+	LogMaxSize    int64         `mapstructure:"log_max_size"` // in megabytes
+	LogMaxFiles   int           `mapstructure:"log_max_files"`
+	Theme         string        `mapstructure:"theme"`
+	WeekStart     string        `mapstructure:"week_start"`
+	DateFormat    string        `mapstructure:"date_format"`
+	TimeFormat    string        `mapstructure:"time_format"`
+	TimeZone      string        `mapstructure:"time_zone"` // times are shown and typed in it, empty for the local one
+	IdleThreshold time.Duration `mapstructure:"idle_threshold"`
+	IdleCommand   string        `mapstructure:"idle_command"` // prints the system idle time, empty uses keys in the TUI only
+	Rounding      struct {
+		Interval time.Duration `mapstructure:"interval"`
+		Mode     string        `mapstructure:"mode"`
+	} `mapstructure:"rounding"`
+	Pomodoro struct {
+		Enabled        bool          `mapstructure:"enabled"`
+		Work           time.Duration `mapstructure:"work"`
+		ShortBreak     time.Duration `mapstructure:"short_break"`
+		LongBreak      time.Duration `mapstructure:"long_break"`
+		LongBreakEvery int           `mapstructure:"long_break_every"`
+	} `mapstructure:"pomodoro"`
+	Backup struct {
+		Dir        string        `mapstructure:"dir"` // empty keeps backups in database_path/backups
+		Interval   time.Duration `mapstructure:"interval"`
+		KeepDaily  int           `mapstructure:"keep_daily"`
+		KeepWeekly int           `mapstructure:"keep_weekly"`
+	} `mapstructure:"backup"`
+	// Keys overrides key bindings by name, e.g. stop: [space, s].
+	Keys map[string][]string `mapstructure:"keys"`
+	// Themes defines additional themes by name.
+	Themes map[string]themes.Colors `mapstructure:"themes"`
+}
+
+// File returns the path of the config file, TIMEKEEPER_CONFIG overrides the default location.
+func File() (string, error) {
+	if f := os.Getenv("TIMEKEEPER_CONFIG"); f != "" {
+		return f, nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yml"), nil
+}
+
+// Dir is the timekeeper folder in the user's config dir.
+func Dir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find config dir: %w", err)
+	}
+	return filepath.Join(configDir, "timekeeper"), nil
+}
+
+func setDefaults(v *viper.Viper) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	v.SetDefault("database_path", dir)
+	v.SetDefault("store", "clover")
+	v.SetDefault("log_file", filepath.Join(os.TempDir(), "timekeeper.log"))
+	v.SetDefault("log_level", "info")
+	v.SetDefault("log_format", "text")
+	v.SetDefault("log_max_size", 10)
+	v.SetDefault("log_max_files", 3)
+	v.SetDefault("theme", "tokyonight")
+	v.SetDefault("week_start", "monday")
+	v.SetDefault("date_format", models.DefaultLayout.Date)
+	v.SetDefault("time_format", models.DefaultLayout.Time)
+	v.SetDefault("time_zone", "")
+	v.SetDefault("idle_threshold", "10m")
+	v.SetDefault("idle_command", "")
+	v.SetDefault("rounding.interval", "0s")
+	v.SetDefault("rounding.mode", models.RoundNearest)
+	v.SetDefault("pomodoro.enabled", false)
+	v.SetDefault("pomodoro.work", "25m")
+	v.SetDefault("pomodoro.short_break", "5m")
+	v.SetDefault("pomodoro.long_break", "15m")
+	v.SetDefault("pomodoro.long_break_every", 4)
+	v.SetDefault("backup.dir", "")
+	v.SetDefault("backup.interval", "24h")
+	v.SetDefault("backup.keep_daily", 7)
+	v.SetDefault("backup.keep_weekly", 4)
+	return nil
+}
+
+// Load reads the config file, writing one with the defaults if there is none yet.
+// An existing file is never rewritten.
+func Load() (Config, error) {
+	var cfg Config
+	file, err := File()
+	if err != nil {
+		return cfg, err
+	}
+	v := viper.New()
+	if err := setDefaults(v); err != nil {
+		return cfg, err
+	}
+	v.SetConfigFile(file)
+	v.SetEnvPrefix("timekeeper")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return cfg, fmt.Errorf("could not create config folder: %w", err)
+		}
+		if err := v.SafeWriteConfigAs(file); err != nil {
+			return cfg, fmt.Errorf("could not write default config: %w", err)
+		}
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return cfg, fmt.Errorf("could not read config %s: %w", file, err)
+	}
+	if err := v.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", file, err)
+	}
+	cfg.DatabasePath = expandHome(cfg.DatabasePath)
+	cfg.LogFile = expandHome(cfg.LogFile)
+	cfg.Backup.Dir = expandHome(cfg.Backup.Dir)
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", file, err)
+	}
+	cfg.registerThemes()
+	return cfg, nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.DatabasePath == "" {
+		errs = append(errs, errors.New("database_path must not be empty"))
+	}
+	if c.Store != "clover" && c.Store != "sqlite" {
+		errs = append(errs, fmt.Errorf("unknown store %q, use clover or sqlite", c.Store))
+	}
+	if c.LogFile == "" {
+		errs = append(errs, errors.New("log_file must not be empty"))
+	}
+	if _, err := log.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("unknown log_format %q, use text or json", c.LogFormat))
+	}
+	if c.LogMaxSize < 0 || c.LogMaxFiles < 0 {
+		errs = append(errs, errors.New("log_max_size and log_max_files must not be negative"))
+	}
+	_, themeErrs := c.userThemes()
+	errs = append(errs, themeErrs...)
+	_, builtin := themes.ByName(c.Theme)
+	_, defined := c.Themes[strings.ToLower(c.Theme)]
+	if !builtin && !defined {
+		errs = append(errs, fmt.Errorf("unknown theme %q, use one of %s or define it under themes", c.Theme, strings.Join(themes.Names(), ", ")))
+	}
+	if _, err := parseWeekday(c.WeekStart); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Layout().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.loadLocation(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.IdleThreshold < 0 {
+		errs = append(errs, errors.New("idle_threshold must not be negative"))
+	}
+	if err := c.PomodoroSettings().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Backup.Interval < 0 || c.Backup.KeepDaily < 0 || c.Backup.KeepWeekly < 0 {
+		errs = append(errs, errors.New("backup interval, keep_daily and keep_weekly must not be negative"))
+	}
+	if err := c.RoundingRule().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.KeyMap(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c Config) Layout() models.Layout {
+	return models.Layout{Date: c.DateFormat, Time: c.TimeFormat}
+}
+
+// TimeParser reads typed times in the zone entries are shown in, see Location.
+func (c Config) TimeParser() timeexpr.Parser {
+	return timeexpr.Parser{Location: c.Location(), FirstWeekday: c.FirstWeekday(), DateLayout: c.DateFormat, TimeLayout: c.TimeFormat}
+}
+
+// Location returns the configured time zone. It replaces time.Local on start,
+// so entries are shown in the zone times are typed in.
+func (c Config) Location() *time.Location {
+	loc, err := c.loadLocation()
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func (c Config) loadLocation() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time_zone %q, use a name like Europe/Berlin", c.TimeZone)
+	}
+	return loc, nil
+}
+
+func (c Config) RoundingRule() models.Rounding {
+	return models.Rounding{Interval: c.Rounding.Interval, Mode: c.Rounding.Mode}
+}
+
+func (c Config) LogOptions() log.Options {
+	return log.Options{
+		Path:     c.LogFile,
+		Level:    c.LogLevel,
+		Format:   c.LogFormat,
+		MaxSize:  c.LogMaxSize << 20,
+		MaxFiles: c.LogMaxFiles,
+	}
+}
+
+func (c Config) PomodoroSettings() models.Pomodoro {
+	return models.Pomodoro{
+		Work:           c.Pomodoro.Work,
+		ShortBreak:     c.Pomodoro.ShortBreak,
+		LongBreak:      c.Pomodoro.LongBreak,
+		LongBreakEvery: c.Pomodoro.LongBreakEvery,
+	}
+}
+
+func (c Config) BackupOptions() backup.Options {
+	dir := c.Backup.Dir
+	if dir == "" {
+		dir = filepath.Join(c.DatabasePath, "backups")
+	}
+	return backup.Options{
+		Dir:        dir,
+		Interval:   c.Backup.Interval,
+		KeepDaily:  c.Backup.KeepDaily,
+		KeepWeekly: c.Backup.KeepWeekly,
+	}
+}
+
+// KeyMap is the default key map with the configured bindings applied.
+func (c Config) KeyMap() (keys.KeyMap, error) {
+	km := keys.Default()
+	err := km.Override(c.Keys)
+	return km, err
+}
+
+// registerThemes makes the user's themes available next to the built-in ones.
+// Their colors were checked by Validate.
+func (c Config) registerThemes() {
+	built, _ := c.userThemes()
+	for name, t := range built {
+		themes.Register(name, t)
+	}
+}
+
+// userThemes builds the themes of the config. A base is either built in or
+// another of them, as long as their bases don't lead back to the theme.
+func (c Config) userThemes() (map[string]themes.Theme, []error) {
+	built := make(map[string]themes.Theme)
+	failed := make(map[string]bool)
+	var errs []error
+	var build func(name string, path []string) (themes.Theme, bool)
+	build = func(name string, path []string) (themes.Theme, bool) {
+		if t, ok := built[name]; ok {
+			return t, true
+		}
+		if failed[name] {
+			return nil, false
+		}
+		for i, n := range path {
+			if n == name {
+				failed[name] = true
+				errs = append(errs, fmt.Errorf("theme %s: its base leads back to it, %s", name, strings.Join(append(path[i:], name), " -> ")))
+				return nil, false
+			}
+		}
+		colors := c.Themes[name]
+		baseName := colors.BaseName()
+		var base themes.Theme
+		// a theme replacing a built-in one of the same name is based on that
+		if _, own := c.Themes[baseName]; own && baseName != name {
+			b, ok := build(baseName, append(path, name))
+			if !ok {
+				// the error is reported for the base
+				failed[name] = true
+				return nil, false
+			}
+			base = b
+		} else if b, ok := themes.ByName(baseName); ok {
+			base = b
+		} else {
+			failed[name] = true
+			errs = append(errs, fmt.Errorf("theme %s: unknown base theme %q", name, colors.Base))
+			return nil, false
+		}
+		t, err := themes.FromBase(colors, base)
+		if err != nil {
+			failed[name] = true
+			errs = append(errs, fmt.Errorf("theme %s: %w", name, err))
+			return nil, false
+		}
+		built[name] = t
+		return t, true
+	}
+	names := make([]string, 0, len(c.Themes))
+	for name := range c.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		build(name, nil)
+	}
+	return built, errs
+}
+
+// FirstWeekday is the configured start of the week, Monday if invalid.
+func (c Config) FirstWeekday() time.Weekday {
+	d, err := parseWeekday(c.WeekStart)
+	if err != nil {
+		return time.Monday
+	}
+	return d
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown week_start %q, use a weekday like monday", s)
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
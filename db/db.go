@@ -8,8 +8,6 @@ import (
 	"github.com/ostafen/clover/v2/document"
 	"github.com/ostafen/clover/v2/query"
 	"os"
-	"path"
-	"path/filepath"
 	"time"
 )
 
@@ -21,54 +19,95 @@ type entry struct {
 	End      *time.Time `clover:"end"`
 	Start    time.Time  `clover:"start"`
 	Content  string     `clover:"content"`
+	Project  string     `clover:"project"`
+	Tags     []string   `clover:"tags"`
+	Pauses   []pause    `clover:"pauses"`
 }
 
-func OpenDatabase() *clover.DB {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		log.Errorf("could not find config dir. Aborting. %s", err)
+type pause struct {
+	Start time.Time  `clover:"start"`
+	End   *time.Time `clover:"end"`
+}
+
+// CloverStore keeps entries, projects and clients in clover collections.
+type CloverStore struct {
+	db *clover.DB
+}
+
+// OpenDatabase opens the clover database in the folder path, creates missing
+// collections and upgrades entries written by older versions. If upgrading
+// fails, the store is returned along with a *MigrationError.
+func OpenDatabase(path string) (*CloverStore, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("could not create database folder: %w", err)
 	}
-	db, err := clover.Open(filepath.Join(configDir, "timekeeper"))
+	db, err := clover.Open(path)
 	if err != nil {
-		log.Errorf("could not open database. Aborting. %s", err)
+		return nil, fmt.Errorf("could not open database %s: %w", path, err)
 	}
 
-	hasEntriesCollection, err := db.HasCollection(collectionName)
-	if err != nil {
-		log.Errorf("could not check if there are entries collection. Aborting. %s", err)
-	}
-	if !hasEntriesCollection {
-		err = db.CreateCollection(collectionName)
+	for _, name := range []string{collectionName, projectCollectionName, clientCollectionName} {
+		hasCollection, err := db.HasCollection(name)
 		if err != nil {
-			log.Errorf("could not create collection. Aborting. %s", err)
+			db.Close()
+			return nil, fmt.Errorf("could not check if there is a %s collection: %w", name, err)
 		}
+		if !hasCollection {
+			err = db.CreateCollection(name)
+			if err != nil {
+				db.Close()
+				return nil, fmt.Errorf("could not create collection %s: %w", name, err)
+			}
+		}
+	}
+	s := &CloverStore{db: db}
+	if err := s.migrate(path); err != nil {
+		return s, err
 	}
-	return db
+	return s, nil
 }
 
-func LoadEntries(db *clover.DB) []*models.Entry {
-	docs, err := db.FindAll(query.NewQuery(collectionName).Sort(query.SortOption{Field: "start", Direction: -1}))
+// LoadEntries returns all entries, newest first. Documents that can't be read
+// are skipped and logged, the readable entries are returned along with an error then.
+func (s *CloverStore) LoadEntries() ([]*models.Entry, error) {
+	docs, err := s.db.FindAll(query.NewQuery(collectionName).Sort(query.SortOption{Field: "start", Direction: -1}))
 	if err != nil {
-		log.Errorf("could not list entries. Aborting. %s", err)
+		return nil, fmt.Errorf("could not list entries: %w", err)
 	}
 	items := make([]*models.Entry, 0, len(docs))
+	skipped := 0
 	for _, doc := range docs {
 		entry, err := unmarshallDoc(doc)
 		if err != nil {
-			log.Errorf("loading entries failed: %s", err)
+			log.Warnf("skipping entry %s: %v", doc.ObjectId(), err)
+			skipped++
+			continue
 		}
 		items = append(items, entry)
 	}
-	return items
+	if err := s.attachProjects(items...); err != nil {
+		return items, fmt.Errorf("loading projects failed: %w", err)
+	}
+	if skipped > 0 {
+		return items, fmt.Errorf("%d entries could not be read, see the log", skipped)
+	}
+	return items, nil
 }
 
-func AddEntry(db *clover.DB, e *models.Entry) error {
+func (s *CloverStore) AddEntry(e *models.Entry) error {
+	if err := s.EnsureProject(e.Project); err != nil {
+		return err
+	}
 	doc := document.NewDocument()
 	doc.Set("name", e.Name)
 	doc.Set("start", e.Start)
 	doc.Set("end", e.End)
 	doc.Set("content", e.Content)
-	id, err := db.InsertOne("entries", doc)
+	doc.Set("project", projectId(e))
+	doc.Set("tags", e.Tags)
+	doc.Set("pauses", pauseDocs(e))
+	doc.Set(schemaField, schemaVersion)
+	id, err := s.db.InsertOne("entries", doc)
 	if err != nil {
 		return fmt.Errorf("could not write to database: %w", err)
 	}
@@ -76,52 +115,137 @@ func AddEntry(db *clover.DB, e *models.Entry) error {
 	return nil
 }
 
-func CloseDatabase(db *clover.DB) {
+func (s *CloverStore) Close() error {
 	log.Infof("closing database file")
-	db.ExportCollection(collectionName, path.Join(os.TempDir(), "timekeeper.json"))
-	err := db.Close()
-	if err != nil {
-		log.Errorf("could not close db. %s", err)
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("could not close db: %w", err)
 	}
+	return nil
 }
 
-func GetRunning(db *clover.DB) (*models.Entry, error) {
-	entries, err := db.FindAll(query.NewQuery(collectionName).Where(query.Field("end").IsNil()))
+// GetRunning returns the entry without an end or nil if no task is running.
+func (s *CloverStore) GetRunning() (*models.Entry, error) {
+	entries, err := s.db.FindAll(query.NewQuery(collectionName).Where(query.Field("end").IsNil()))
 	if err != nil {
-		log.Errorf("could not list entries. Aborting. %s", err)
+		return nil, fmt.Errorf("could not list running entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
 	}
 	if len(entries) > 1 {
-		return nil, fmt.Errorf("more than one running tasks")
+		ids := make([]string, 0, len(entries))
+		for _, doc := range entries {
+			ids = append(ids, doc.ObjectId())
+		}
+		return nil, &RunningError{Ids: ids}
 	}
-	return unmarshallDoc(entries[0])
-}
-
-func ExportEntries(db *clover.DB) error {
-	return db.ExportCollection(collectionName, "entries.json")
+	running, err := unmarshallDoc(entries[0])
+	if err != nil {
+		return nil, err
+	}
+	return running, s.attachProjects(running)
 }
 
-func UpdateEntry(db *clover.DB, e *models.Entry) error {
-	return db.UpdateById(collectionName, e.ObjectId, func(doc *document.Document) *document.Document {
+func (s *CloverStore) UpdateEntry(e *models.Entry) error {
+	if err := s.EnsureProject(e.Project); err != nil {
+		return err
+	}
+	return s.db.UpdateById(collectionName, e.ObjectId, func(doc *document.Document) *document.Document {
 		doc.Set("name", e.Name)
 		doc.Set("start", e.Start)
 		doc.Set("end", e.End)
 		doc.Set("content", e.Content)
+		doc.Set("project", projectId(e))
+		doc.Set("tags", e.Tags)
+		doc.Set("pauses", pauseDocs(e))
+		doc.Set(schemaField, schemaVersion)
 		return doc
 	})
 }
 
+// SwitchEntry updates ended and adds next. Clover has no transactions spanning
+// both, so ended is put back the way it was if adding next fails.
+func (s *CloverStore) SwitchEntry(ended, next *models.Entry) error {
+	before, err := s.db.FindById(collectionName, ended.ObjectId)
+	if err != nil {
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	if before == nil {
+		return fmt.Errorf("could not switch entries: there is no entry %s", ended.ObjectId)
+	}
+	if err := s.UpdateEntry(ended); err != nil {
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	if err := s.AddEntry(next); err != nil {
+		if rerr := s.db.ReplaceById(collectionName, ended.ObjectId, before); rerr != nil {
+			log.Errorf("could not restore entry %s after a failed switch: %v", ended.ObjectId, rerr)
+		}
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	return nil
+}
+
+func (s *CloverStore) DeleteEntry(e *models.Entry) error {
+	if err := s.db.DeleteById(collectionName, e.ObjectId); err != nil {
+		return fmt.Errorf("could not delete entry: %w", err)
+	}
+	return nil
+}
+
 func unmarshallDoc(doc *document.Document) (*models.Entry, error) {
 	entry := &entry{}
 	err := doc.Unmarshal(entry)
 	if err != nil {
 		return nil, fmt.Errorf("could not unmarshal document. %s", err)
 	}
-	doc.ObjectId()
-	return &models.Entry{
+	e := &models.Entry{
 		ObjectId: doc.ObjectId(),
 		Start:    entry.Start,
 		End:      entry.End,
 		Name:     entry.Name,
 		Content:  entry.Content,
-	}, nil
+		Tags:     entry.Tags,
+	}
+	for _, p := range entry.Pauses {
+		e.Pauses = append(e.Pauses, models.Pause{Start: p.Start, End: p.End})
+	}
+	if entry.Project != "" {
+		e.Project = &models.Project{ObjectId: entry.Project}
+	}
+	return e, nil
+}
+
+func pauseDocs(e *models.Entry) []map[string]interface{} {
+	docs := make([]map[string]interface{}, 0, len(e.Pauses))
+	for _, p := range e.Pauses {
+		docs = append(docs, map[string]interface{}{"start": p.Start, "end": p.End})
+	}
+	return docs
+}
+
+// LoadEntriesBetween returns all entries started in [from, to), newest first.
+// A zero from or to leaves that side of the range open.
+func (s *CloverStore) LoadEntriesBetween(from, to time.Time) ([]*models.Entry, error) {
+	q := query.NewQuery(collectionName)
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		q = q.Where(query.Field("start").GtEq(from).And(query.Field("start").Lt(to)))
+	case !from.IsZero():
+		q = q.Where(query.Field("start").GtEq(from))
+	case !to.IsZero():
+		q = q.Where(query.Field("start").Lt(to))
+	}
+	docs, err := s.db.FindAll(q.Sort(query.SortOption{Field: "start", Direction: -1}))
+	if err != nil {
+		return nil, fmt.Errorf("could not list entries: %w", err)
+	}
+	items := make([]*models.Entry, 0, len(docs))
+	for _, doc := range docs {
+		entry, err := unmarshallDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, entry)
+	}
+	return items, s.attachProjects(items...)
 }
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/log"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+// schemaField holds the schema version of an entry document, documents
+// written before versioning have none and count as version 0.
+const schemaField = "schema"
+
+// migration upgrades an entry document from the previous schema version.
+type migration struct {
+	description string
+	up          func(doc *document.Document) error
+}
+
+// entryMigrations upgrade entry documents, entryMigrations[i] turns version i into i+1.
+// Append new ones, never change or reorder released ones.
+var entryMigrations = []migration{
+	{
+		description: "add missing project and tags fields",
+		up: func(doc *document.Document) error {
+			if !doc.Has("start") {
+				return errors.New("entry has no start")
+			}
+			if !doc.Has("project") || doc.Get("project") == nil {
+				doc.Set("project", "")
+			}
+			if !doc.Has("tags") || doc.Get("tags") == nil {
+				doc.Set("tags", []string{})
+			}
+			if !doc.Has("content") {
+				doc.Set("content", "")
+			}
+			return nil
+		},
+	},
+	{
+		description: "add pauses",
+		up: func(doc *document.Document) error {
+			if !doc.Has("pauses") || doc.Get("pauses") == nil {
+				doc.Set("pauses", []interface{}{})
+			}
+			return nil
+		},
+	},
+}
+
+// schemaVersion is the version new entry documents are written with.
+var schemaVersion = len(entryMigrations)
+
+// MigrationError lists the entries that could not be upgraded. The store is
+// usable when it is returned, the entries keep their old schema.
+type MigrationError struct {
+	Backup string // backup taken before migrating, empty if none
+	Errs   []error
+}
+
+func (e *MigrationError) Error() string {
+	msg := fmt.Sprintf("%d entries could not be upgraded to schema %d", len(e.Errs), schemaVersion)
+	if e.Backup != "" {
+		msg += ", backup in " + e.Backup
+	}
+	return msg + ": " + errors.Join(e.Errs...).Error()
+}
+
+func (e *MigrationError) Unwrap() []error {
+	return e.Errs
+}
+
+// migrate upgrades all entry documents older than schemaVersion. The entries
+// collection is exported to dir/backups first, nothing is changed if that fails.
+func (s *CloverStore) migrate(dir string) error {
+	docs, err := s.db.FindAll(query.NewQuery(collectionName))
+	if err != nil {
+		return &MigrationError{Errs: []error{fmt.Errorf("could not list entries: %w", err)}}
+	}
+	var outdated []*document.Document
+	oldest := schemaVersion
+	for _, doc := range docs {
+		if v := schemaOf(doc); v < schemaVersion {
+			outdated = append(outdated, doc)
+			oldest = min(oldest, v)
+		} else if v > schemaVersion {
+			log.Warnf("entry %s has schema %d, this timekeeper only knows up to %d", doc.ObjectId(), v, schemaVersion)
+		}
+	}
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	backup, err := s.backupEntries(dir, oldest)
+	if err != nil {
+		return &MigrationError{Errs: []error{fmt.Errorf("not migrating, backup failed: %w", err)}}
+	}
+	log.Infof("upgrading %d entries to schema %d, backup in %s", len(outdated), schemaVersion, backup)
+
+	var errs []error
+	for _, doc := range outdated {
+		upgraded, err := upgrade(doc)
+		if err == nil {
+			err = s.db.ReplaceById(collectionName, doc.ObjectId(), upgraded)
+		}
+		if err != nil {
+			log.Errorf("could not upgrade entry %s: %v", doc.ObjectId(), err)
+			errs = append(errs, fmt.Errorf("entry %s: %w", doc.ObjectId(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return &MigrationError{Backup: backup, Errs: errs}
+	}
+	return nil
+}
+
+// upgrade runs the pending migrations on a copy of doc.
+func upgrade(doc *document.Document) (*document.Document, error) {
+	upgraded := doc.Copy()
+	for v := schemaOf(doc); v < schemaVersion; v++ {
+		if err := entryMigrations[v].up(upgraded); err != nil {
+			return nil, fmt.Errorf("schema %d to %d (%s): %w", v, v+1, entryMigrations[v].description, err)
+		}
+		upgraded.Set(schemaField, v+1)
+	}
+	return upgraded, nil
+}
+
+// backupEntries exports the entries collection before entries of schema are
+// upgraded to schemaVersion. Entries that fail to upgrade are tried again on
+// every open, the backup is only written the first time.
+func (s *CloverStore) backupEntries(dir string, schema int) (string, error) {
+	backups := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backups, 0755); err != nil {
+		return "", err
+	}
+	prefix := fmt.Sprintf("entries-schema%d-to%d-", schema, schemaVersion)
+	existing, err := filepath.Glob(filepath.Join(backups, prefix+"*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(existing) > 0 {
+		return existing[len(existing)-1], nil
+	}
+	file := filepath.Join(backups, prefix+time.Now().Format("20060102-150405")+".json")
+	if err := s.db.ExportCollection(collectionName, file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// schemaOf returns the schema version of doc, 0 if it has none.
+func schemaOf(doc *document.Document) int {
+	switch v := doc.Get(schemaField).(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
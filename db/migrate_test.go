@@ -0,0 +1,129 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+// storeWithDocs returns a clover database folder holding docs as they are,
+// without a schema version unless they set one.
+func storeWithDocs(t *testing.T, docs ...*document.Document) string {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := OpenDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Insert(collectionName, docs...); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func v0Doc(name string) *document.Document {
+	doc := document.NewDocument()
+	doc.Set("name", name)
+	doc.Set("start", at(12, 9, 0))
+	doc.Set("end", ptr(at(12, 10, 0)))
+	return doc
+}
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "backups", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestUpgrade(t *testing.T) {
+	upgraded, err := upgrade(v0Doc("review"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := schemaOf(upgraded); v != schemaVersion {
+		t.Errorf("upgraded to schema %d, want %d", v, schemaVersion)
+	}
+	for _, field := range []string{"project", "tags", "content", "pauses"} {
+		if !upgraded.Has(field) || upgraded.Get(field) == nil {
+			t.Errorf("upgraded document has no %s", field)
+		}
+	}
+}
+
+func TestMigrateOnOpen(t *testing.T) {
+	dir := storeWithDocs(t, v0Doc("review"))
+	s, err := OpenDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.LoadEntries()
+	if err != nil || len(entries) != 1 || entries[0].Name != "review" {
+		t.Fatalf("LoadEntries after the upgrade = %v, %v", entries, err)
+	}
+	docs, err := s.db.FindAll(query.NewQuery(collectionName))
+	if err != nil || len(docs) != 1 || schemaOf(docs[0]) != schemaVersion {
+		t.Errorf("the document wasn't upgraded: %v", err)
+	}
+	s.Close()
+	if files := backups(t, dir); len(files) != 1 {
+		t.Errorf("%d backups written, want 1", len(files))
+	}
+
+	// the store is up to date now, opening it again changes nothing
+	s, err = OpenDatabase(dir)
+	if err != nil {
+		t.Fatalf("opening the upgraded store failed: %v", err)
+	}
+	s.Close()
+	if files := backups(t, dir); len(files) != 1 {
+		t.Errorf("%d backups after opening the upgraded store, want 1", len(files))
+	}
+}
+
+func TestMigrateUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := os.Stat(filepath.Join(dir, "backups")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("opening a new store touched the backups: %v", err)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	broken := document.NewDocument()
+	broken.Set("name", "no start")
+	dir := storeWithDocs(t, v0Doc("review"), broken)
+
+	for i := 0; i < 2; i++ {
+		s, err := OpenDatabase(dir)
+		var migration *MigrationError
+		if !errors.As(err, &migration) || s == nil {
+			t.Fatalf("OpenDatabase = %v, want a usable store and a *MigrationError", err)
+		}
+		if len(migration.Errs) != 1 || migration.Backup == "" {
+			t.Errorf("MigrationError lists %d errors and backup %q, want 1 and a backup", len(migration.Errs), migration.Backup)
+		}
+		// the readable entry is upgraded anyway
+		if entries, err := s.LoadEntries(); len(entries) == 0 || entries[0].Name != "review" {
+			t.Errorf("LoadEntries = %v, %v, want the upgraded entry", entries, err)
+		}
+		s.Close()
+	}
+	// the broken entry is tried again, the backup isn't written again
+	if files := backups(t, dir); len(files) != 1 {
+		t.Errorf("%d backups after opening twice, want 1", len(files))
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/danielroehrig/timekeeper/log"
+	"github.com/danielroehrig/timekeeper/models"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+const (
+	projectCollectionName = "projects"
+	clientCollectionName  = "clients"
+)
+
+type project struct {
+	Name   string `clover:"name"`
+	Client string `clover:"client"`
+}
+
+type client struct {
+	Name string `clover:"name"`
+}
+
+// LoadProjects returns all projects sorted by name with their clients resolved.
+func (s *CloverStore) LoadProjects() ([]*models.Project, error) {
+	clients, err := s.loadClients()
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.db.FindAll(query.NewQuery(projectCollectionName).Sort(query.SortOption{Field: "name", Direction: 1}))
+	if err != nil {
+		return nil, fmt.Errorf("could not list projects: %w", err)
+	}
+	projects := make([]*models.Project, 0, len(docs))
+	for _, doc := range docs {
+		p := &project{}
+		if err := doc.Unmarshal(p); err != nil {
+			return nil, fmt.Errorf("could not unmarshal project. %s", err)
+		}
+		projects = append(projects, &models.Project{
+			ObjectId: doc.ObjectId(),
+			Name:     p.Name,
+			Client:   clients[p.Client],
+		})
+	}
+	return projects, nil
+}
+
+// EnsureProject looks up the project and its client by name and inserts whatever is missing.
+// The ids of p and p.Client are set afterward.
+func (s *CloverStore) EnsureProject(p *models.Project) error {
+	if p == nil || p.ObjectId != "" {
+		return nil
+	}
+	clientId := ""
+	if p.Client != nil {
+		if err := s.ensureClient(p.Client); err != nil {
+			return err
+		}
+		clientId = p.Client.ObjectId
+	}
+	doc, err := s.db.FindFirst(query.NewQuery(projectCollectionName).
+		Where(query.Field("name").Eq(p.Name).And(query.Field("client").Eq(clientId))))
+	if err != nil {
+		return fmt.Errorf("could not look up project: %w", err)
+	}
+	if doc != nil {
+		p.ObjectId = doc.ObjectId()
+		return nil
+	}
+	doc = document.NewDocument()
+	doc.Set("name", p.Name)
+	doc.Set("client", clientId)
+	id, err := s.db.InsertOne(projectCollectionName, doc)
+	if err != nil {
+		return fmt.Errorf("could not write project to database: %w", err)
+	}
+	p.ObjectId = id
+	return nil
+}
+
+func (s *CloverStore) ensureClient(c *models.Client) error {
+	if c.ObjectId != "" {
+		return nil
+	}
+	doc, err := s.db.FindFirst(query.NewQuery(clientCollectionName).Where(query.Field("name").Eq(c.Name)))
+	if err != nil {
+		return fmt.Errorf("could not look up client: %w", err)
+	}
+	if doc != nil {
+		c.ObjectId = doc.ObjectId()
+		return nil
+	}
+	doc = document.NewDocument()
+	doc.Set("name", c.Name)
+	id, err := s.db.InsertOne(clientCollectionName, doc)
+	if err != nil {
+		return fmt.Errorf("could not write client to database: %w", err)
+	}
+	c.ObjectId = id
+	return nil
+}
+
+func (s *CloverStore) loadClients() (map[string]*models.Client, error) {
+	docs, err := s.db.FindAll(query.NewQuery(clientCollectionName))
+	if err != nil {
+		return nil, fmt.Errorf("could not list clients: %w", err)
+	}
+	clients := make(map[string]*models.Client, len(docs))
+	for _, doc := range docs {
+		c := &client{}
+		if err := doc.Unmarshal(c); err != nil {
+			return nil, fmt.Errorf("could not unmarshal client. %s", err)
+		}
+		clients[doc.ObjectId()] = &models.Client{ObjectId: doc.ObjectId(), Name: c.Name}
+	}
+	return clients, nil
+}
+
+// attachProjects replaces the id-only projects set by unmarshallDoc with the stored ones.
+func (s *CloverStore) attachProjects(entries ...*models.Entry) error {
+	projects, err := s.LoadProjects()
+	if err != nil {
+		return err
+	}
+	byId := make(map[string]*models.Project, len(projects))
+	for _, p := range projects {
+		byId[p.ObjectId] = p
+	}
+	dangling := make(map[string]bool)
+	for _, e := range entries {
+		if e.Project == nil {
+			continue
+		}
+		id := e.Project.ObjectId
+		if e.Project = byId[id]; e.Project == nil && !dangling[id] {
+			// the entry is shown without a project, its document still has the id
+			dangling[id] = true
+			log.Warnf("entry %s refers to project %s, which does not exist", e.ObjectId, id)
+		}
+	}
+	return nil
+}
+
+func projectId(e *models.Entry) string {
+	if e.Project == nil {
+		return ""
+	}
+	return e.Project.ObjectId
+}
@@ -0,0 +1,577 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/log"
+	"github.com/danielroehrig/timekeeper/models"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTime is how times are stored: UTC with a fixed width, so text comparison orders them.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// migrations upgrade the schema one version at a time. The version reached is
+// kept in PRAGMA user_version. Never change a released migration, append a new one.
+var migrations = []string{
+	`CREATE TABLE clients (
+		id   TEXT PRIMARY KEY,
+		name TEXT NOT NULL
+	);
+	CREATE TABLE projects (
+		id        TEXT PRIMARY KEY,
+		name      TEXT NOT NULL,
+		client_id TEXT REFERENCES clients(id)
+	);
+	CREATE TABLE entries (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		start      TEXT NOT NULL,
+		end        TEXT,
+		content    TEXT NOT NULL DEFAULT '',
+		project_id TEXT REFERENCES projects(id)
+	);
+	CREATE INDEX entries_start ON entries(start);
+	CREATE TABLE entry_tags (
+		entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		tag      TEXT NOT NULL,
+		PRIMARY KEY (entry_id, tag)
+	);`,
+	`CREATE TABLE entry_pauses (
+		entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		start    TEXT NOT NULL,
+		end      TEXT
+	);
+	CREATE INDEX entry_pauses_entry ON entry_pauses(entry_id);`,
+}
+
+// SQLiteStore keeps entries in a SQLite file that standard tools can query.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database file and brings its schema up to date.
+func OpenSQLite(file string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, fmt.Errorf("could not create database folder: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("could not open database %s: %w", file, err)
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not migrate database %s: %w", file, err)
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than this timekeeper knows (%d)", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		log.Infof("migrating database schema to version %d", i+1)
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		// PRAGMA takes no parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	log.Infof("closing database file")
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("could not close db: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LoadEntries() ([]*models.Entry, error) {
+	return s.LoadEntriesBetween(time.Time{}, time.Time{})
+}
+
+func (s *SQLiteStore) LoadEntriesBetween(from, to time.Time) ([]*models.Entry, error) {
+	var where []string
+	var args []any
+	if !from.IsZero() {
+		where = append(where, "start >= ?")
+		args = append(args, formatTime(from))
+	}
+	if !to.IsZero() {
+		where = append(where, "start < ?")
+		args = append(args, formatTime(to))
+	}
+	q := "SELECT id, name, start, end, content, project_id FROM entries"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	return s.queryEntries(q+" ORDER BY start DESC", args...)
+}
+
+func (s *SQLiteStore) GetRunning() (*models.Entry, error) {
+	entries, err := s.queryEntries("SELECT id, name, start, end, content, project_id FROM entries WHERE end IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("could not list running entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if len(entries) > 1 {
+		ids := make([]string, 0, len(entries))
+		for _, e := range entries {
+			ids = append(ids, e.ObjectId)
+		}
+		return nil, &RunningError{Ids: ids}
+	}
+	return entries[0], nil
+}
+
+// queryEntries runs q and loads the projects, tags and pauses of the entries it returns.
+func (s *SQLiteStore) queryEntries(q string, args ...any) ([]*models.Entry, error) {
+	entries, projectIds, err := s.scanEntries(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ObjectId
+	}
+	projects, err := s.projectsById(projectIds)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tagsByEntry(ids)
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.pausesByEntry(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if projectIds[i] != "" {
+			e.Project = projects[projectIds[i]]
+		}
+		e.Tags = tags[e.ObjectId]
+		e.Pauses = pauses[e.ObjectId]
+	}
+	return entries, nil
+}
+
+// scanEntries returns the entries of q along with the project id of each, empty for none.
+func (s *SQLiteStore) scanEntries(q string, args ...any) ([]*models.Entry, []string, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list entries: %w", err)
+	}
+	defer rows.Close()
+	entries := make([]*models.Entry, 0)
+	var projectIds []string
+	for rows.Next() {
+		var start string
+		var end, project sql.NullString
+		e := &models.Entry{}
+		if err := rows.Scan(&e.ObjectId, &e.Name, &start, &end, &e.Content, &project); err != nil {
+			return nil, nil, fmt.Errorf("could not read entry: %w", err)
+		}
+		if e.Start, err = parseTime(start); err != nil {
+			return nil, nil, fmt.Errorf("entry %s: %w", e.ObjectId, err)
+		}
+		if end.Valid {
+			t, err := parseTime(end.String)
+			if err != nil {
+				return nil, nil, fmt.Errorf("entry %s: %w", e.ObjectId, err)
+			}
+			e.End = &t
+		}
+		entries = append(entries, e)
+		projectIds = append(projectIds, project.String)
+	}
+	return entries, projectIds, rows.Err()
+}
+
+// maxParams keeps the id lists of a query well below SQLite's limit of parameters.
+const maxParams = 500
+
+// forIds runs q for ids in chunks, q has a %s where the placeholders of a chunk
+// go, like "WHERE id IN (%s)". scan is called for every row.
+func (s *SQLiteStore) forIds(q string, ids []string, scan func(rows *sql.Rows) error) error {
+	for len(ids) > 0 {
+		chunk := ids[:min(len(ids), maxParams)]
+		ids = ids[len(chunk):]
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		rows, err := s.db.Query(fmt.Sprintf(q, placeholders), args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) tagsByEntry(ids []string) (map[string][]string, error) {
+	tags := make(map[string][]string)
+	err := s.forIds("SELECT entry_id, tag FROM entry_tags WHERE entry_id IN (%s) ORDER BY entry_id, rowid", ids, func(rows *sql.Rows) error {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("could not read tag: %w", err)
+		}
+		tags[id] = append(tags[id], tag)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *SQLiteStore) pausesByEntry(ids []string) (map[string][]models.Pause, error) {
+	pauses := make(map[string][]models.Pause)
+	err := s.forIds("SELECT entry_id, start, end FROM entry_pauses WHERE entry_id IN (%s) ORDER BY entry_id, start", ids, func(rows *sql.Rows) error {
+		var id, start string
+		var end sql.NullString
+		if err := rows.Scan(&id, &start, &end); err != nil {
+			return fmt.Errorf("could not read pause: %w", err)
+		}
+		p := models.Pause{}
+		var err error
+		if p.Start, err = parseTime(start); err != nil {
+			return fmt.Errorf("pause of entry %s: %w", id, err)
+		}
+		if end.Valid {
+			t, err := parseTime(end.String)
+			if err != nil {
+				return fmt.Errorf("pause of entry %s: %w", id, err)
+			}
+			p.End = &t
+		}
+		pauses[id] = append(pauses[id], p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list pauses: %w", err)
+	}
+	return pauses, nil
+}
+
+func (s *SQLiteStore) AddEntry(e *models.Entry) error {
+	if err := s.EnsureProject(e.Project); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not write to database: %w", err)
+	}
+	id, err := insertEntry(tx, e)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not write to database: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not write to database: %w", err)
+	}
+	e.ObjectId = id
+	return nil
+}
+
+func (s *SQLiteStore) UpdateEntry(e *models.Entry) error {
+	if err := s.EnsureProject(e.Project); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not update entry: %w", err)
+	}
+	if err := updateEntry(tx, e); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not update entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not update entry: %w", err)
+	}
+	return nil
+}
+
+// SwitchEntry updates ended and adds next in one transaction.
+func (s *SQLiteStore) SwitchEntry(ended, next *models.Entry) error {
+	if err := s.EnsureProject(ended.Project); err != nil {
+		return err
+	}
+	if err := s.EnsureProject(next.Project); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	err = updateEntry(tx, ended)
+	var id string
+	if err == nil {
+		id, err = insertEntry(tx, next)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not switch entries: %w", err)
+	}
+	next.ObjectId = id
+	return nil
+}
+
+// insertEntry adds e and returns its id, e itself is left unchanged.
+func insertEntry(tx *sql.Tx, e *models.Entry) (string, error) {
+	// entries moved over from another store keep their id
+	id := e.ObjectId
+	if id == "" {
+		var err error
+		if id, err = newId(); err != nil {
+			return "", err
+		}
+	}
+	_, err := tx.Exec("INSERT INTO entries (id, name, start, end, content, project_id) VALUES (?, ?, ?, ?, ?, ?)",
+		id, e.Name, formatTime(e.Start), nullTime(e.End), e.Content, nullString(projectId(e)))
+	if err == nil {
+		err = writeTags(tx, id, e.Tags)
+	}
+	if err == nil {
+		err = writePauses(tx, id, e.Pauses)
+	}
+	return id, err
+}
+
+func updateEntry(tx *sql.Tx, e *models.Entry) error {
+	res, err := tx.Exec("UPDATE entries SET name = ?, start = ?, end = ?, content = ?, project_id = ? WHERE id = ?",
+		e.Name, formatTime(e.Start), nullTime(e.End), e.Content, nullString(projectId(e)), e.ObjectId)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("there is no entry %s", e.ObjectId)
+	}
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id = ?", e.ObjectId); err != nil {
+		return err
+	}
+	if err := writeTags(tx, e.ObjectId, e.Tags); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_pauses WHERE entry_id = ?", e.ObjectId); err != nil {
+		return err
+	}
+	return writePauses(tx, e.ObjectId, e.Pauses)
+}
+
+func (s *SQLiteStore) DeleteEntry(e *models.Entry) error {
+	if _, err := s.db.Exec("DELETE FROM entries WHERE id = ?", e.ObjectId); err != nil {
+		return fmt.Errorf("could not delete entry: %w", err)
+	}
+	return nil
+}
+
+func writeTags(tx *sql.Tx, id string, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO entry_tags (entry_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePauses(tx *sql.Tx, id string, pauses []models.Pause) error {
+	for _, p := range pauses {
+		if _, err := tx.Exec("INSERT INTO entry_pauses (entry_id, start, end) VALUES (?, ?, ?)", id, formatTime(p.Start), nullTime(p.End)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const projectQuery = "SELECT p.id, p.name, c.id, c.name FROM projects p LEFT JOIN clients c ON c.id = p.client_id"
+
+// LoadProjects returns all projects sorted by name with their clients resolved.
+func (s *SQLiteStore) LoadProjects() ([]*models.Project, error) {
+	rows, err := s.db.Query(projectQuery + " ORDER BY p.name")
+	if err != nil {
+		return nil, fmt.Errorf("could not list projects: %w", err)
+	}
+	defer rows.Close()
+	clients := make(map[string]*models.Client)
+	projects := make([]*models.Project, 0)
+	for rows.Next() {
+		p, err := scanProject(rows, clients)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// projectsById loads the projects with the given ids, empty ids are skipped.
+func (s *SQLiteStore) projectsById(ids []string) (map[string]*models.Project, error) {
+	byId := make(map[string]*models.Project)
+	var wanted []string
+	for _, id := range ids {
+		if _, ok := byId[id]; !ok && id != "" {
+			byId[id] = nil
+			wanted = append(wanted, id)
+		}
+	}
+	clients := make(map[string]*models.Client)
+	err := s.forIds(projectQuery+" WHERE p.id IN (%s)", wanted, func(rows *sql.Rows) error {
+		p, err := scanProject(rows, clients)
+		if err == nil {
+			byId[p.ObjectId] = p
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list projects: %w", err)
+	}
+	return byId, nil
+}
+
+// scanProject reads a row of projectQuery. Projects of one client share it
+// like in the clover store.
+func scanProject(rows *sql.Rows, clients map[string]*models.Client) (*models.Project, error) {
+	var clientId, clientName sql.NullString
+	p := &models.Project{}
+	if err := rows.Scan(&p.ObjectId, &p.Name, &clientId, &clientName); err != nil {
+		return nil, fmt.Errorf("could not read project: %w", err)
+	}
+	if clientId.Valid {
+		c, ok := clients[clientId.String]
+		if !ok {
+			c = &models.Client{ObjectId: clientId.String, Name: clientName.String}
+			clients[c.ObjectId] = c
+		}
+		p.Client = c
+	}
+	return p, nil
+}
+
+// EnsureProject looks up the project and its client by name and inserts whatever is missing.
+// The ids of p and p.Client are set afterward.
+func (s *SQLiteStore) EnsureProject(p *models.Project) error {
+	if p == nil || p.ObjectId != "" {
+		return nil
+	}
+	var clientId sql.NullString
+	if p.Client != nil {
+		if err := s.ensureClient(p.Client); err != nil {
+			return err
+		}
+		clientId = nullString(p.Client.ObjectId)
+	}
+	err := s.db.QueryRow("SELECT id FROM projects WHERE name = ? AND client_id IS ?", p.Name, clientId).Scan(&p.ObjectId)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up project: %w", err)
+	}
+	id, err := newId()
+	if err != nil {
+		return fmt.Errorf("could not write project to database: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO projects (id, name, client_id) VALUES (?, ?, ?)", id, p.Name, clientId); err != nil {
+		return fmt.Errorf("could not write project to database: %w", err)
+	}
+	p.ObjectId = id
+	return nil
+}
+
+func (s *SQLiteStore) ensureClient(c *models.Client) error {
+	if c.ObjectId != "" {
+		return nil
+	}
+	err := s.db.QueryRow("SELECT id FROM clients WHERE name = ?", c.Name).Scan(&c.ObjectId)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up client: %w", err)
+	}
+	id, err := newId()
+	if err != nil {
+		return fmt.Errorf("could not write client to database: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO clients (id, name) VALUES (?, ?)", id, c.Name); err != nil {
+		return fmt.Errorf("could not write client to database: %w", err)
+	}
+	c.ObjectId = id
+	return nil
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate an id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTime)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(sqliteTime, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Local(), nil
+}
+
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return nullString(formatTime(*t))
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, berlin)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+// forEachStore runs test against a fresh store of every kind.
+func forEachStore(t *testing.T, test func(t *testing.T, s EntryStore)) {
+	for _, kind := range []string{Clover, SQLite} {
+		t.Run(kind, func(t *testing.T) {
+			s, err := Open(kind, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			test(t, s)
+		})
+	}
+}
+
+func load(t *testing.T, s EntryStore, id string) *models.Entry {
+	t.Helper()
+	entries, err := s.LoadEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.ObjectId == id {
+			return e
+		}
+	}
+	t.Fatalf("entry %s is missing", id)
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func checkEntry(t *testing.T, got, want *models.Entry) {
+	t.Helper()
+	if got.Name != want.Name || got.Content != want.Content || !got.Start.Equal(want.Start) || !sameTime(got.End, want.End) {
+		t.Errorf("loaded %q %s-%v %q, want %q %s-%v %q", got.Name, got.Start, got.End, got.Content, want.Name, want.Start, want.End, want.Content)
+	}
+	if fmt.Sprint(got.Tags) != fmt.Sprint(want.Tags) {
+		t.Errorf("loaded tags %v, want %v", got.Tags, want.Tags)
+	}
+	if len(got.Pauses) != len(want.Pauses) {
+		t.Fatalf("loaded %d pauses, want %d", len(got.Pauses), len(want.Pauses))
+	}
+	for i, p := range got.Pauses {
+		if !p.Start.Equal(want.Pauses[i].Start) || !sameTime(p.End, want.Pauses[i].End) {
+			t.Errorf("loaded pause %s-%v, want %s-%v", p.Start, p.End, want.Pauses[i].Start, want.Pauses[i].End)
+		}
+	}
+	switch {
+	case (got.Project == nil) != (want.Project == nil):
+		t.Errorf("loaded project %v, want %v", got.Project, want.Project)
+	case got.Project != nil && got.Project.String() != want.Project.String():
+		t.Errorf("loaded project %s, want %s", got.Project, want.Project)
+	}
+}
+
+func TestAddAndUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EntryStore) {
+		e := &models.Entry{
+			Name:    "review",
+			Start:   at(12, 9, 0),
+			End:     ptr(at(12, 10, 30)),
+			Content: "notes",
+			Project: &models.Project{Name: "web", Client: &models.Client{Name: "acme"}},
+			Tags:    []string{"meeting", "backend"},
+			Pauses:  []models.Pause{{Start: at(12, 9, 30), End: ptr(at(12, 9, 45))}},
+		}
+		if err := s.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ObjectId == "" {
+			t.Fatal("AddEntry left the id empty")
+		}
+		checkEntry(t, load(t, s, e.ObjectId), e)
+
+		e.Name, e.End, e.Tags, e.Project = "planning", ptr(at(12, 11, 0)), []string{"meeting"}, nil
+		e.Pauses = append(e.Pauses, models.Pause{Start: at(12, 10, 0), End: ptr(at(12, 10, 5))})
+		if err := s.UpdateEntry(e); err != nil {
+			t.Fatal(err)
+		}
+		checkEntry(t, load(t, s, e.ObjectId), e)
+	})
+}
+
+func TestSwitchAndDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EntryStore) {
+		running := &models.Entry{Name: "review", Start: at(12, 9, 0)}
+		if err := s.AddEntry(running); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.GetRunning(); err != nil || got == nil || got.ObjectId != running.ObjectId {
+			t.Fatalf("GetRunning = %v, %v, want %s", got, err, running.ObjectId)
+		}
+
+		running.End = ptr(at(12, 10, 0))
+		next := &models.Entry{Name: "planning", Start: at(12, 10, 0)}
+		if err := s.SwitchEntry(running, next); err != nil {
+			t.Fatal(err)
+		}
+		if next.ObjectId == "" {
+			t.Fatal("SwitchEntry left the id of next empty")
+		}
+		checkEntry(t, load(t, s, running.ObjectId), running)
+		if got, err := s.GetRunning(); err != nil || got == nil || got.ObjectId != next.ObjectId {
+			t.Fatalf("GetRunning after the switch = %v, %v, want %s", got, err, next.ObjectId)
+		}
+
+		if err := s.DeleteEntry(next); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.GetRunning(); err != nil || got != nil {
+			t.Fatalf("GetRunning after deleting the running task = %v, %v", got, err)
+		}
+		if entries, err := s.LoadEntries(); err != nil || len(entries) != 1 {
+			t.Fatalf("LoadEntries after the delete = %d entries, %v", len(entries), err)
+		}
+	})
+}
+
+func TestSeveralRunning(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EntryStore) {
+		var ids []string
+		for _, start := range []time.Time{at(12, 9, 0), at(12, 10, 0)} {
+			e := &models.Entry{Name: "review", Start: start}
+			if err := s.AddEntry(e); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, e.ObjectId)
+		}
+		_, err := s.GetRunning()
+		var running *RunningError
+		if !errors.As(err, &running) {
+			t.Fatalf("GetRunning = %v, want a *RunningError", err)
+		}
+		sort.Strings(ids)
+		sort.Strings(running.Ids)
+		if fmt.Sprint(running.Ids) != fmt.Sprint(ids) {
+			t.Errorf("RunningError lists %v, want %v", running.Ids, ids)
+		}
+	})
+}
+
+func TestTimesAcrossDST(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EntryStore) {
+		// clocks in Berlin jump from 02:00 to 03:00 on March 31st
+		before := time.Date(2024, time.March, 31, 1, 30, 0, 123456789, berlin)
+		after := time.Date(2024, time.March, 31, 3, 30, 0, 0, berlin)
+		e := &models.Entry{Name: "night shift", Start: before, End: &after}
+		if err := s.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+		got := load(t, s, e.ObjectId)
+		if !got.Start.Equal(before) || !got.End.Equal(after) || got.End.Sub(got.Start) != after.Sub(before) {
+			t.Errorf("loaded %s-%s, want %s-%s", got.Start, got.End, before, after)
+		}
+		between, err := s.LoadEntriesBetween(time.Date(2024, time.March, 31, 0, 0, 0, 0, berlin), time.Date(2024, time.April, 1, 0, 0, 0, 0, berlin))
+		if err != nil || len(between) != 1 {
+			t.Errorf("LoadEntriesBetween on March 31st = %d entries, %v", len(between), err)
+		}
+		if between, err := s.LoadEntriesBetween(before.Add(time.Nanosecond), time.Time{}); err != nil || len(between) != 0 {
+			t.Errorf("LoadEntriesBetween after the start = %d entries, %v", len(between), err)
+		}
+	})
+}
+
+func TestSQLiteSwitchRollsBack(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	running := &models.Entry{Name: "review", Start: at(12, 9, 0)}
+	if err := s.AddEntry(running); err != nil {
+		t.Fatal(err)
+	}
+	// next takes the id of an existing entry, inserting it fails
+	ended := *running
+	ended.End = ptr(at(12, 10, 0))
+	next := &models.Entry{ObjectId: running.ObjectId, Name: "planning", Start: at(12, 10, 0)}
+	if err := s.SwitchEntry(&ended, next); err == nil {
+		t.Fatal("SwitchEntry with a duplicate id succeeded")
+	}
+	if got := load(t, s, running.ObjectId); got.End != nil {
+		t.Errorf("the end of %s was stored although the switch failed", got.Name)
+	}
+}
+
+func TestSQLiteLoadsManyEntries(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), SQLiteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// more entries than fit into one IN list
+	n := 2*maxParams + 1
+	for i := 0; i < n; i++ {
+		start := at(1, 0, 0).Add(time.Duration(i) * time.Hour)
+		e := &models.Entry{Name: fmt.Sprint(i), Start: start, End: ptr(start.Add(time.Minute)), Tags: []string{"t"},
+			Pauses: []models.Pause{{Start: start, End: ptr(start.Add(time.Second))}}}
+		if i%2 == 0 {
+			e.Project = &models.Project{Name: fmt.Sprint("p", i%4)}
+		}
+		if err := s.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := s.LoadEntries()
+	if err != nil || len(entries) != n {
+		t.Fatalf("LoadEntries = %d entries, %v, want %d", len(entries), err, n)
+	}
+	for i, e := range entries {
+		if i > 0 && !e.Start.Before(entries[i-1].Start) {
+			t.Fatalf("entries are not sorted newest first at %s", e.Name)
+		}
+		var number int
+		fmt.Sscan(e.Name, &number)
+		if len(e.Tags) != 1 || len(e.Pauses) != 1 || (e.Project != nil) != (number%2 == 0) {
+			t.Fatalf("entry %s loaded with tags %v, pauses %v, project %v", e.Name, e.Tags, e.Pauses, e.Project)
+		}
+	}
+}
+
+func TestSQLiteTimeFormat(t *testing.T) {
+	times := []time.Time{
+		at(12, 9, 0),
+		at(12, 9, 0).Add(time.Millisecond),
+		time.Date(2024, time.March, 12, 8, 0, 0, 1, time.UTC),
+		time.Date(2024, time.October, 27, 2, 30, 0, 0, berlin),
+	}
+	for _, tm := range times {
+		s := formatTime(tm)
+		if len(s) != len(sqliteTime) {
+			t.Errorf("formatTime(%s) = %q, want %d characters", tm, s, len(sqliteTime))
+		}
+		back, err := parseTime(s)
+		if err != nil || !back.Equal(tm) {
+			t.Errorf("parseTime(%q) = %s, %v, want %s", s, back, err, tm)
+		}
+	}
+	// stored times sort like the instants they stand for
+	for _, a := range times {
+		for _, b := range times {
+			if (formatTime(a) < formatTime(b)) != a.Before(b) {
+				t.Errorf("%s and %s sort differently as text", a, b)
+			}
+		}
+	}
+}
+
+func TestNewId(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := newId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 32 || seen[id] {
+			t.Fatalf("newId = %q, want 32 new hex characters", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// EntryStore is where entries and their projects are kept. Entries come back
+// newest first with their projects and clients resolved.
+type EntryStore interface {
+	LoadEntries() ([]*models.Entry, error)
+	// LoadEntriesBetween returns entries started in [from, to), a zero time leaves that side open.
+	LoadEntriesBetween(from, to time.Time) ([]*models.Entry, error)
+	// GetRunning returns the entry without an end, nil if no task is running.
+	// It returns a *RunningError if several entries have no end.
+	GetRunning() (*models.Entry, error)
+	// AddEntry stores a new entry and sets its id.
+	AddEntry(e *models.Entry) error
+	UpdateEntry(e *models.Entry) error
+	// SwitchEntry stores the end of ended and adds next as one operation, either
+	// both are stored or neither. next gets its id like with AddEntry.
+	SwitchEntry(ended, next *models.Entry) error
+	DeleteEntry(e *models.Entry) error
+	LoadProjects() ([]*models.Project, error)
+	Close() error
+}
+
+// RunningError is returned if several entries have no end. Only one task can
+// run at a time, timekeeper doctor closes the others.
+type RunningError struct {
+	Ids []string
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("%d tasks are running (%s), timekeeper doctor can close the orphaned ones", len(e.Ids), strings.Join(e.Ids, ", "))
+}
+
+// Store kinds for the store config key.
+const (
+	Clover = "clover"
+	SQLite = "sqlite"
+)
+
+// SQLiteFile is the name of the SQLite database inside the database folder.
+const SQLiteFile = "timekeeper.sqlite"
+
+// Open opens the store of the given kind in the database folder dir. The store
+// is usable despite an error if it is a *MigrationError.
+func Open(kind, dir string) (EntryStore, error) {
+	switch kind {
+	case Clover:
+		s, err := OpenDatabase(dir)
+		if s == nil {
+			return nil, err
+		}
+		return s, err
+	case SQLite:
+		s, err := OpenSQLite(filepath.Join(dir, SQLiteFile))
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown store %q, use %s or %s", kind, Clover, SQLite)
+}
+
+var (
+	_ EntryStore = (*CloverStore)(nil)
+	_ EntryStore = (*SQLiteStore)(nil)
+)
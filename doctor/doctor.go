@@ -0,0 +1,225 @@
+// Package doctor finds entries that break what the rest of timekeeper relies
+// on, like overlapping entries or several running tasks, and repairs them.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dbaccess "github.com/danielroehrig/timekeeper/db"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Kind is the sort of anomaly. Problems are reported in this order, which is
+// also the order in which their repairs don't get in each other's way.
+type Kind int
+
+const (
+	EmptyName Kind = iota
+	Orphaned
+	Overlap
+	Negative
+	MissingStart
+)
+
+func (k Kind) String() string {
+	switch k {
+	case EmptyName:
+		return "empty-name"
+	case Orphaned:
+		return "orphaned"
+	case Overlap:
+		return "overlap"
+	case Negative:
+		return "negative"
+	default:
+		return "missing-start"
+	}
+}
+
+// Repair is the change that fixes a problem.
+type Repair int
+
+const (
+	// Manual problems have to be fixed in the editor.
+	Manual Repair = iota
+	// Trim ends the entry where the entry it overlaps starts.
+	Trim
+	// Close ends an orphaned running entry.
+	Close
+	// Delete removes the entry.
+	Delete
+)
+
+// Problem is an anomaly of Entry. Other is the first entry Entry overlaps,
+// Also the ones after it. For an orphaned entry Other is the entry started next.
+type Problem struct {
+	Kind  Kind
+	Entry *models.Entry
+	Other *models.Entry
+	Also  []*models.Entry
+	// now is the end of running entries when the problem was found
+	now time.Time
+}
+
+// Detail describes the problem with the ids of the entries involved.
+func (p Problem) Detail() string {
+	e := p.Entry
+	switch p.Kind {
+	case EmptyName:
+		return "has no name"
+	case Orphaned:
+		return fmt.Sprintf("%q still runs, %s %q started after it", e.Name, p.Other.ObjectId, p.Other.Name)
+	case Overlap:
+		overlap := end(e, p.now)
+		if o := end(p.Other, p.now); o.Before(overlap) {
+			overlap = o
+		}
+		detail := fmt.Sprintf("%q overlaps %s %q by %s", e.Name, p.Other.ObjectId, p.Other.Name, overlap.Sub(p.Other.Start).Round(time.Second))
+		for _, o := range p.Also {
+			detail += fmt.Sprintf(", %s %q", o.ObjectId, o.Name)
+		}
+		return detail
+	case Negative:
+		if e.End.Equal(e.Start) {
+			return fmt.Sprintf("%q ends when it starts", e.Name)
+		}
+		return fmt.Sprintf("%q ends %s before it starts", e.Name, e.Start.Sub(*e.End).Round(time.Second))
+	default:
+		if e.End == nil {
+			return fmt.Sprintf("%q has no start", e.Name)
+		}
+		return fmt.Sprintf("%q has an end but no start", e.Name)
+	}
+}
+
+// Repair returns how the problem can be fixed.
+func (p Problem) Repair() Repair {
+	switch p.Kind {
+	case EmptyName:
+		return Delete
+	case Orphaned:
+		return Close
+	case Overlap:
+		// the running task is stopped or adjusted by the user, an entry
+		// starting with the other one would be left without a duration
+		if p.Entry.End == nil || !p.Other.Start.After(p.Entry.Start) {
+			return Manual
+		}
+		return Trim
+	}
+	return Manual
+}
+
+// End returns where Trim and Close end the entry unless told otherwise: where
+// the entry after it starts.
+func (p Problem) End() time.Time {
+	return p.Other.Start
+}
+
+// Check returns the problems of entries, the order of entries doesn't matter.
+// Running entries count until now.
+func Check(entries []*models.Entry, now time.Time) []Problem {
+	sorted := make([]*models.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	var problems []Problem
+	add := func(kind Kind, e, other *models.Entry) {
+		problems = append(problems, Problem{Kind: kind, Entry: e, Other: other, now: now})
+	}
+	// only the latest running entry is the running task
+	running := -1
+	for i, e := range sorted {
+		if e.End == nil && !e.Start.IsZero() {
+			running = i
+		}
+	}
+	var last *models.Entry
+	var lastEnd time.Time
+	// an entry reaching over several others is trimmed once, to the first of them
+	overlaps := make(map[*models.Entry]int)
+	for i, e := range sorted {
+		if strings.TrimSpace(e.Name) == "" {
+			add(EmptyName, e, nil)
+		}
+		switch {
+		case e.Start.IsZero():
+			add(MissingStart, e, nil)
+			continue
+		case e.End != nil && !e.End.After(e.Start):
+			add(Negative, e, nil)
+			continue
+		case e.End == nil && i != running:
+			add(Orphaned, e, sorted[i+1])
+			continue
+		}
+		if last != nil && e.Start.Before(lastEnd) {
+			if j, ok := overlaps[last]; ok {
+				problems[j].Also = append(problems[j].Also, e)
+			} else {
+				overlaps[last] = len(problems)
+				add(Overlap, last, e)
+			}
+		}
+		if end(e, now).After(lastEnd) {
+			last, lastEnd = e, end(e, now)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Kind < problems[j].Kind
+	})
+	return problems
+}
+
+// Fix applies the repair of p to the entry in store. Trim and Close end the
+// entry at end, Close checks that the entry can end there.
+func Fix(store dbaccess.EntryStore, p Problem, end time.Time) error {
+	e := p.Entry
+	switch p.Repair() {
+	case Delete:
+		return store.DeleteEntry(e)
+	case Close:
+		if err := models.ValidateTimes(e.Start, &end, time.Now()); err != nil {
+			return err
+		}
+		e.Stop(end)
+	case Trim:
+		// trimming only ever shortens the entry
+		if e.End == nil || !end.Before(*e.End) {
+			return nil
+		}
+		e.End = &end
+		e.Pauses = pausesUntil(e.Pauses, end)
+	default:
+		return errors.New("has to be fixed by hand, edit the entry in timekeeper")
+	}
+	return store.UpdateEntry(e)
+}
+
+// pausesUntil drops the pauses starting at or after end and ends the others by then.
+func pausesUntil(pauses []models.Pause, end time.Time) []models.Pause {
+	var kept []models.Pause
+	for _, p := range pauses {
+		if !p.Start.Before(end) {
+			break
+		}
+		if p.End == nil || p.End.After(end) {
+			p.End = &end
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+func end(e *models.Entry, now time.Time) time.Time {
+	if e.End == nil {
+		return now
+	}
+	return *e.End
+}
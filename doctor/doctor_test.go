@@ -0,0 +1,30 @@
+package doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func TestCheckTrimsLongEntryOnce(t *testing.T) {
+	at := func(hour, minute int) *time.Time {
+		t := time.Date(2024, time.March, 13, hour, minute, 0, 0, time.UTC)
+		return &t
+	}
+	long := &models.Entry{ObjectId: "long", Name: "long", Start: *at(9, 0), End: at(13, 0)}
+	e1 := &models.Entry{ObjectId: "e1", Name: "e1", Start: *at(10, 0), End: at(10, 30)}
+	e2 := &models.Entry{ObjectId: "e2", Name: "e2", Start: *at(11, 0), End: at(11, 30)}
+
+	problems := Check([]*models.Entry{e2, long, e1}, *at(18, 0))
+	if len(problems) != 1 {
+		t.Fatalf("Check found %d problems, want 1: %v", len(problems), problems)
+	}
+	p := problems[0]
+	if p.Kind != Overlap || p.Entry != long || p.Other != e1 || len(p.Also) != 1 || p.Also[0] != e2 {
+		t.Errorf("Check = %+v, want long overlapping e1 and e2", p)
+	}
+	if p.Repair() != Trim || !p.End().Equal(*at(10, 0)) {
+		t.Errorf("repair is %v to %s, want a trim to 10:00", p.Repair(), p.End())
+	}
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// CSV writes a header and one row per entry. Times are RFC3339, tags are separated by ";".
+type CSV struct{}
+
+var csvHeader = []string{"id", "start", "end", "duration_seconds", "name", "client", "project", "tags", "content"}
+
+func (CSV) Extension() string {
+	return "csv"
+}
+
+func (CSV) Write(w io.Writer, entries []*models.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		end := ""
+		seconds := ""
+		if e.End != nil {
+			end = e.End.Format(time.RFC3339)
+			seconds = strconv.FormatInt(int64(e.Duration(*e.End).Seconds()), 10)
+		}
+		err := cw.Write([]string{
+			e.ObjectId,
+			e.Start.Format(time.RFC3339),
+			end,
+			seconds,
+			e.Name,
+			clientName(e.Project),
+			projectName(e.Project),
+			strings.Join(e.Tags, ";"),
+			e.Content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
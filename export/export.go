@@ -0,0 +1,163 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Formatter writes entries in one export format.
+type Formatter interface {
+	Extension() string
+	Write(w io.Writer, entries []*models.Entry) error
+}
+
+var formatters = map[string]Formatter{}
+
+// Register makes a formatter available under name, replacing any previous one.
+func Register(name string, f Formatter) {
+	formatters[name] = f
+}
+
+func Lookup(name string) (Formatter, bool) {
+	f, ok := formatters[strings.ToLower(name)]
+	return f, ok
+}
+
+// Formats lists the names of all registered formatters.
+func Formats() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("csv", CSV{})
+	Register("json", JSON{})
+	Register("ics", ICS{})
+}
+
+type Options struct {
+	// From and To limit the export to entries started in [From, To), zero values leave the range open.
+	From time.Time
+	To   time.Time
+	// Project matches either the project name or "client/project", case-insensitive.
+	Project string
+	// Tags have to be present on an entry, all of them.
+	Tags   []string
+	Format string
+	// Path is the output file, empty or "-" writes to Out.
+	Path string
+	// Out receives the export without a path, os.Stdout if nil.
+	Out io.Writer
+}
+
+// FormatFor returns the format given in the options or derives it from the path's extension.
+func (o Options) FormatFor() string {
+	if o.Format != "" {
+		return strings.ToLower(o.Format)
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(o.Path), "."))
+}
+
+// Filter keeps the finished entries matching the options. Running entries are
+// never exported since their end and duration are still changing.
+func Filter(entries []*models.Entry, o Options) []*models.Entry {
+	tags := models.NormalizeTags(o.Tags)
+	filtered := make([]*models.Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.End == nil {
+			continue
+		}
+		if !o.From.IsZero() && e.Start.Before(o.From) {
+			continue
+		}
+		if !o.To.IsZero() && !e.Start.Before(o.To) {
+			continue
+		}
+		if o.Project != "" && !matchesProject(e.Project, o.Project) {
+			continue
+		}
+		if !hasTags(e, tags) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Start.Before(filtered[j].Start)
+	})
+	return filtered
+}
+
+// Export filters the entries and writes them to the configured path.
+// It returns the number of exported entries.
+func Export(entries []*models.Entry, o Options) (int, error) {
+	format := o.FormatFor()
+	f, ok := Lookup(format)
+	if !ok {
+		return 0, fmt.Errorf("unknown export format %q, use one of %s", format, strings.Join(Formats(), ", "))
+	}
+	filtered := Filter(entries, o)
+	if o.Path == "" || o.Path == "-" {
+		out := o.Out
+		if out == nil {
+			out = os.Stdout
+		}
+		return len(filtered), f.Write(out, filtered)
+	}
+	out, err := os.Create(o.Path)
+	if err != nil {
+		return 0, fmt.Errorf("could not create export file: %w", err)
+	}
+	if err := f.Write(out, filtered); err != nil {
+		out.Close()
+		return 0, fmt.Errorf("could not write export: %w", err)
+	}
+	return len(filtered), out.Close()
+}
+
+func matchesProject(p *models.Project, filter string) bool {
+	if p == nil {
+		return false
+	}
+	return strings.EqualFold(p.Name, filter) || strings.EqualFold(p.String(), filter)
+}
+
+func hasTags(e *models.Entry, tags []string) bool {
+	for _, t := range tags {
+		found := false
+		for _, et := range e.Tags {
+			if et == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func clientName(p *models.Project) string {
+	if p == nil || p.Client == nil {
+		return ""
+	}
+	return p.Client.Name
+}
+
+func projectName(p *models.Project) string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func finished(name string, start time.Time, d time.Duration) *models.Entry {
+	end := start.Add(d)
+	return &models.Entry{ObjectId: name, Name: name, Start: start, End: &end}
+}
+
+func TestFilter(t *testing.T) {
+	web := &models.Project{Name: "web", Client: &models.Client{Name: "acme"}}
+	review := finished("review", at(12, 9, 0), time.Hour)
+	review.Project, review.Tags = web, []string{"meeting", "backend"}
+	planning := finished("planning", at(11, 9, 0), time.Hour)
+	planning.Tags = []string{"meeting"}
+	standup := finished("standup", at(13, 9, 0), time.Hour)
+	running := &models.Entry{ObjectId: "running", Name: "running", Start: at(12, 12, 0), Project: web}
+	entries := []*models.Entry{standup, running, review, planning}
+
+	tests := []struct {
+		name string
+		o    Options
+		want string
+	}{
+		{"running entries are left out, oldest first", Options{}, "planning review standup"},
+		{"range", Options{From: at(12, 0, 0), To: at(13, 9, 0)}, "review"},
+		{"project by name", Options{Project: "WEB"}, "review"},
+		{"project with client", Options{Project: "acme/web"}, "review"},
+		{"tags", Options{Tags: []string{"#Meeting"}}, "planning review"},
+		{"all tags", Options{Tags: []string{"meeting", "backend"}}, "review"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, e := range Filter(entries, tt.o) {
+				names = append(names, e.Name)
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("Filter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportToOut(t *testing.T) {
+	entries := []*models.Entry{finished("review", at(12, 9, 0), time.Hour), {Name: "running", Start: at(12, 12, 0)}}
+	var out bytes.Buffer
+	n, err := Export(entries, Options{Path: "-", Format: "json", Out: &out})
+	if err != nil || n != 1 {
+		t.Fatalf("Export = %d, %v", n, err)
+	}
+	var doc Document
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != SchemaVersion || len(doc.Entries) != 1 || doc.Entries[0].DurationSeconds != 3600 {
+		t.Errorf("exported %+v", doc)
+	}
+
+	if _, err := Export(entries, Options{Path: "-", Format: "xml", Out: &out}); err == nil {
+		t.Error("Export to an unknown format succeeded")
+	}
+	file := filepath.Join(t.TempDir(), "entries.csv")
+	if n, err := Export(entries, Options{Path: file}); err != nil || n != 1 {
+		t.Errorf("Export to %s = %d, %v", file, n, err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	e := finished("review, part 2", at(12, 9, 0), 90*time.Minute)
+	e.Project = &models.Project{Name: "web", Client: &models.Client{Name: "acme"}}
+	e.Tags = []string{"meeting", "backend"}
+	e.Content = "line one\nline \"two\""
+	var out bytes.Buffer
+	if err := (CSV{}).Write(&out, []*models.Entry{e}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,start,end,duration_seconds,name,client,project,tags,content\n" +
+		"\"review, part 2\",2024-03-12T09:00:00Z,2024-03-12T10:30:00Z,5400,\"review, part 2\",acme,web,meeting;backend,\"line one\nline \"\"two\"\"\"\n"
+	if out.String() != want {
+		t.Errorf("CSV wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
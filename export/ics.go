@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// ICS writes an iCalendar file with one VEVENT per entry.
+type ICS struct{}
+
+const icsTimeFormat = "20060102T150405Z"
+
+func (ICS) Extension() string {
+	return "ics"
+}
+
+func (ICS) Write(w io.Writer, entries []*models.Entry) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now().UTC().Format(icsTimeFormat)
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//timekeeper//timekeeper//EN")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	for _, e := range entries {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+e.ObjectId+"@timekeeper")
+		writeLine(bw, "DTSTAMP:"+now)
+		writeLine(bw, "DTSTART:"+e.Start.UTC().Format(icsTimeFormat))
+		if e.End != nil {
+			writeLine(bw, "DTEND:"+e.End.UTC().Format(icsTimeFormat))
+		}
+		summary := e.Name
+		if e.Project != nil {
+			summary += " @" + e.Project.String()
+		}
+		writeLine(bw, "SUMMARY:"+escapeText(summary))
+		if e.Content != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(e.Content))
+		}
+		if len(e.Tags) > 0 {
+			tags := make([]string, 0, len(e.Tags))
+			for _, t := range e.Tags {
+				tags = append(tags, escapeText(t))
+			}
+			writeLine(bw, "CATEGORIES:"+strings.Join(tags, ","))
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine folds content lines longer than 75 octets as required by RFC 5545
+// without splitting multibyte characters.
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space which counts against the limit
+		limit = 74
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
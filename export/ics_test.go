@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a, b; c", `a\, b\; c`},
+		{`C:\temp`, `C:\\temp`},
+		{"one\ntwo\r\nthree", `one\ntwo\nthree`},
+	}
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLineFolds(t *testing.T) {
+	tests := []string{
+		"SUMMARY:short",
+		"SUMMARY:" + strings.Repeat("x", 67),
+		"SUMMARY:" + strings.Repeat("x", 68),
+		"SUMMARY:" + strings.Repeat("x", 300),
+		// three byte characters must not be split across lines
+		"SUMMARY:" + strings.Repeat("€", 100),
+		"SUMMARY:a" + strings.Repeat("ü", 100),
+	}
+	for _, line := range tests {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		writeLine(w, line)
+		w.Flush()
+		out := buf.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("%q does not end with CRLF", out)
+		}
+		parts := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, p := range parts {
+			if len(p) > 75 {
+				t.Errorf("line %d of %q has %d octets", i, line, len(p))
+			}
+			if i > 0 && !strings.HasPrefix(p, " ") {
+				t.Errorf("continuation line %q doesn't start with a space", p)
+			}
+			if !utf8.ValidString(p) {
+				t.Errorf("line %q splits a character", p)
+			}
+		}
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolding gives %q, want %q", unfolded, line)
+		}
+	}
+}
+
+func TestICS(t *testing.T) {
+	e := finished("review", at(12, 9, 0), time.Hour)
+	e.ObjectId = "42"
+	e.Project = &models.Project{Name: "web", Client: &models.Client{Name: "acme"}}
+	e.Tags = []string{"meeting", "a,b"}
+	e.Content = "agenda; see\nnotes"
+	var out bytes.Buffer
+	if err := (ICS{}).Write(&out, []*models.Entry{e}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"BEGIN:VEVENT\r\nUID:42@timekeeper\r\n",
+		"DTSTART:20240312T090000Z\r\nDTEND:20240312T100000Z\r\n",
+		"SUMMARY:review @acme/web\r\n",
+		`DESCRIPTION:agenda\; see\nnotes` + "\r\n",
+		`CATEGORIES:meeting,a\,b` + "\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("ICS output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
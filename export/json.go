@@ -0,0 +1,83 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// SchemaVersion is increased whenever a field of Document or Entry changes in an incompatible way.
+// The schema is documented in docs/export.md.
+const SchemaVersion = 1
+
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Entries    []Entry   `json:"entries"`
+}
+
+type Entry struct {
+	Id              string     `json:"id"`
+	Name            string     `json:"name"`
+	Client          string     `json:"client"`
+	Project         string     `json:"project"`
+	Tags            []string   `json:"tags"`
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Pauses          []Pause    `json:"pauses"`
+	Content         string     `json:"content"`
+}
+
+// Pause is a pause of an entry, End is null while the entry is paused.
+type Pause struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// NewEntry converts an entry to its JSON form. Running entries count until now.
+func NewEntry(e *models.Entry, now time.Time) Entry {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	pauses := make([]Pause, 0, len(e.Pauses))
+	for _, p := range e.Pauses {
+		pauses = append(pauses, Pause{Start: p.Start, End: p.End})
+	}
+	return Entry{
+		Id:              e.ObjectId,
+		Name:            e.Name,
+		Client:          clientName(e.Project),
+		Project:         projectName(e.Project),
+		Tags:            tags,
+		Start:           e.Start,
+		End:             e.End,
+		DurationSeconds: int64(e.Duration(now).Seconds()),
+		Pauses:          pauses,
+		Content:         e.Content,
+	}
+}
+
+type JSON struct{}
+
+func (JSON) Extension() string {
+	return "json"
+}
+
+func (JSON) Write(w io.Writer, entries []*models.Entry) error {
+	now := time.Now()
+	doc := Document{
+		Version:    SchemaVersion,
+		ExportedAt: now,
+		Entries:    make([]Entry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		doc.Entries = append(doc.Entries, NewEntry(e, now))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,60 @@
+// Package idle asks the system how long the user has been inactive.
+package idle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source reports the time since the last user input.
+type Source interface {
+	Idle() (time.Duration, error)
+}
+
+// Command runs a shell command that prints the idle time, either as a Go
+// duration like 90s or as a number of milliseconds. The last number in the
+// output is used, so wrappers like the "(uint64 5000,)" of gdbus work too.
+type Command string
+
+// timeout keeps a hanging command from piling up checks.
+const timeout = 5 * time.Second
+
+func (c Command) Idle() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", string(c)).Output()
+	if err != nil {
+		return 0, fmt.Errorf("idle command %q failed: %w", string(c), err)
+	}
+	d, err := Parse(string(out))
+	if err != nil {
+		return 0, fmt.Errorf("idle command %q: %w", string(c), err)
+	}
+	return d, nil
+}
+
+var number = regexp.MustCompile(`\d+`)
+
+// Parse reads an idle time printed by a command.
+func Parse(out string) (time.Duration, error) {
+	out = strings.TrimSpace(out)
+	if d, err := time.ParseDuration(out); err == nil {
+		return d, nil
+	}
+	// the type in wrappers like "(uint64 5000,)" comes before the value
+	numbers := number.FindAllString(out, -1)
+	if len(numbers) == 0 {
+		return 0, errors.New("output has no idle time: " + out)
+	}
+	ms, err := strconv.ParseInt(numbers[len(numbers)-1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,31 @@
+package idle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		out  string
+		want time.Duration
+	}{
+		{"(uint64 5000,)\n", 5 * time.Second},
+		{"1234\n", 1234 * time.Millisecond},
+		{"90s", 90 * time.Second},
+		{"idle: 250", 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.out)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.out, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.out, got, tt.want)
+		}
+	}
+	if _, err := Parse("no idle time"); err == nil {
+		t.Error("Parse without a number succeeded")
+	}
+}
@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Fields a CSV column can be mapped to. Start and end are either given as one
+// column each or split into date and time columns.
+const (
+	FieldName      = "name"
+	FieldStart     = "start"
+	FieldEnd       = "end"
+	FieldStartDate = "start_date"
+	FieldStartTime = "start_time"
+	FieldEndDate   = "end_date"
+	FieldEndTime   = "end_time"
+	FieldDuration  = "duration"
+	FieldClient    = "client"
+	FieldProject   = "project"
+	FieldTags      = "tags"
+	FieldContent   = "content"
+)
+
+var fields = []string{FieldName, FieldStart, FieldEnd, FieldStartDate, FieldStartTime, FieldEndDate, FieldEndTime,
+	FieldDuration, FieldClient, FieldProject, FieldTags, FieldContent}
+
+// Mapping maps entry fields to CSV column headers.
+type Mapping map[string]string
+
+// Presets for the exports of timekeeper itself and other trackers.
+var Presets = map[string]Mapping{
+	"timekeeper": {
+		FieldName: "name", FieldStart: "start", FieldEnd: "end", FieldClient: "client",
+		FieldProject: "project", FieldTags: "tags", FieldContent: "content",
+	},
+	"toggl": {
+		FieldName: "Description", FieldStartDate: "Start date", FieldStartTime: "Start time",
+		FieldEndDate: "End date", FieldEndTime: "End time", FieldDuration: "Duration",
+		FieldClient: "Client", FieldProject: "Project", FieldTags: "Tags",
+	},
+	"clockify": {
+		FieldName: "Description", FieldStartDate: "Start Date", FieldStartTime: "Start Time",
+		FieldEndDate: "End Date", FieldEndTime: "End Time", FieldDuration: "Duration (h)",
+		FieldClient: "Client", FieldProject: "Project", FieldTags: "Tags",
+	},
+}
+
+var presetOrder = []string{"timekeeper", "toggl", "clockify"}
+
+// ParseMapping reads "field=Column,field=Column" as given on the command line.
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("mapping %q is not written as field=column", pair)
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q, use one of %s", field, strings.Join(fields, ", "))
+		}
+		m[field] = strings.TrimSpace(column)
+	}
+	return m, nil
+}
+
+func isField(name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CSV reads comma separated files with a header row. Without a mapping the
+// preset matching the header best is used.
+type CSV struct {
+	Mapping Mapping
+}
+
+func (c CSV) Read(r io.Reader, loc *time.Location) ([]Record, []Rejection, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	mapping := c.Mapping
+	if mapping == nil {
+		if mapping = detect(header); mapping == nil {
+			return nil, nil, fmt.Errorf("unknown csv columns, pass a mapping like name=Description,start=Start")
+		}
+	}
+	columns := map[string]int{}
+	for field, column := range mapping {
+		for i, h := range header {
+			if strings.EqualFold(h, column) {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns[FieldName]; !ok {
+		return nil, nil, fmt.Errorf("column %q for the name is missing", mapping[FieldName])
+	}
+	_, hasStart := columns[FieldStart]
+	_, hasStartDate := columns[FieldStartDate]
+	if !hasStart && !hasStartDate {
+		return nil, nil, fmt.Errorf("no column for the start is present")
+	}
+
+	var records []Record
+	var rejected []Rejection
+	line := 1
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			rejected = append(rejected, Rejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		get := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		e, err := entryFromRow(get, loc)
+		if err != nil {
+			rejected = append(rejected, Rejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		records = append(records, Record{Line: line, Entry: e})
+	}
+	return records, rejected, nil
+}
+
+// detect picks the preset that has the name and start columns and matches the
+// most columns of header. Headers are matched ignoring case, so the presets of
+// trackers with similar exports, like toggl and clockify, are told apart by
+// the columns only one of them has.
+func detect(header []string) Mapping {
+	var best Mapping
+	bestCount := 0
+	for _, name := range presetOrder {
+		preset := Presets[name]
+		found := true
+		for _, field := range []string{FieldName, FieldStart, FieldStartDate} {
+			column, ok := preset[field]
+			if ok && !containsFold(header, column) {
+				found = false
+			}
+		}
+		if !found {
+			continue
+		}
+		count := 0
+		for _, column := range preset {
+			if containsFold(header, column) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = preset, count
+		}
+	}
+	return best
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func entryFromRow(get func(string) string, loc *time.Location) (*models.Entry, error) {
+	startValue := get(FieldStart)
+	if startValue == "" {
+		startValue = strings.TrimSpace(get(FieldStartDate) + " " + get(FieldStartTime))
+	}
+	if startValue == "" {
+		return nil, fmt.Errorf("missing start")
+	}
+	start, err := parseTime(startValue, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+
+	var end *time.Time
+	endValue := get(FieldEnd)
+	if endValue == "" && get(FieldEndTime) != "" {
+		endDate := get(FieldEndDate)
+		if endDate == "" {
+			endDate = get(FieldStartDate)
+		}
+		endValue = strings.TrimSpace(endDate + " " + get(FieldEndTime))
+	}
+	if endValue != "" {
+		t, err := parseTime(endValue, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+		// an end time without its own date that lies before the start crossed midnight
+		if get(FieldEnd) == "" && get(FieldEndDate) == "" && t.Before(start) {
+			t = t.AddDate(0, 0, 1)
+		}
+		end = &t
+	} else if d := get(FieldDuration); d != "" {
+		dur, err := parseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		t := start.Add(dur)
+		end = &t
+	}
+
+	project := models.ParseProject(get(FieldProject))
+	if project != nil && project.Client == nil && get(FieldClient) != "" {
+		project.Client = &models.Client{Name: get(FieldClient)}
+	}
+	return &models.Entry{
+		Name:    get(FieldName),
+		Start:   start,
+		End:     end,
+		Content: get(FieldContent),
+		Project: project,
+		Tags:    splitTags(get(FieldTags)),
+	}, nil
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006 03:04:05 PM",
+	"01/02/2006 03:04 PM",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+}
+
+// parseTime accepts RFC3339 and the usual date time layouts of tracker exports.
+// Times without an offset are read in loc. Slashed dates are month first.
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
+
+// parseDuration reads hh:mm:ss, hh:mm, Go durations like 1h30m and decimal hours like 1.5.
+func parseDuration(s string) (time.Duration, error) {
+	if parts := strings.Split(s, ":"); len(parts) == 2 || len(parts) == 3 {
+		var total time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("unknown duration format %q", s)
+			}
+			total += time.Duration(n) * units[i]
+		}
+		return total, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	if h, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64); err == nil && h >= 0 {
+		return time.Duration(h * float64(time.Hour)), nil
+	}
+	return 0, fmt.Errorf("unknown duration format %q", s)
+}
+
+func splitTags(s string) []string {
+	return models.NormalizeTags(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';'
+	}))
+}
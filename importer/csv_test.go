@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, berlin)
+}
+
+func TestCSVPresets(t *testing.T) {
+	tests := []struct {
+		preset     string
+		csv        string
+		start, end time.Time
+	}{
+		{
+			"timekeeper",
+			"name,start,end,client,project,tags,content\n" +
+				"review,2024-03-12T09:00:00+01:00,2024-03-12T10:30:00+01:00,acme,web,\"meeting,Backend\",notes\n",
+			at(12, 9, 0), at(12, 10, 30),
+		},
+		{
+			"toggl",
+			"User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+				"jo,jo@example.com,acme,web,,review,No,2024-03-12,09:00:00,2024-03-12,10:30:00,01:30:00,\"meeting, Backend\"\n",
+			at(12, 9, 0), at(12, 10, 30),
+		},
+		{
+			"clockify",
+			"\ufeffProject,Client,Description,Task,User,Tags,Billable,Start Date,Start Time,End Date,End Time,Duration (h)\n" +
+				"web,acme,review,,jo,\"meeting, Backend\",No,03/12/2024,09:00 AM,03/12/2024,10:30 AM,1.50\n",
+			at(12, 9, 0), at(12, 10, 30),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			header := strings.Split(strings.SplitN(tt.csv, "\n", 2)[0], ",")
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+			if got := detect(header); fmt.Sprint(got) != fmt.Sprint(Presets[tt.preset]) {
+				t.Errorf("detected %v, want the %s preset", got, tt.preset)
+			}
+			records, rejected, err := CSV{}.Read(strings.NewReader(tt.csv), berlin)
+			if err != nil || len(rejected) > 0 || len(records) != 1 {
+				t.Fatalf("Read = %d records, %v, %v", len(records), rejected, err)
+			}
+			e := records[0].Entry
+			if e.Name != "review" || !e.Start.Equal(tt.start) || e.End == nil || !e.End.Equal(tt.end) {
+				t.Errorf("read %q %s-%v, want review %s-%s", e.Name, e.Start, e.End, tt.start, tt.end)
+			}
+			if e.Project.String() != "acme/web" || fmt.Sprint(e.Tags) != "[meeting backend]" {
+				t.Errorf("read project %s and tags %v", e.Project, e.Tags)
+			}
+			if records[0].Line != 2 {
+				t.Errorf("record is on line %d, want 2", records[0].Line)
+			}
+		})
+	}
+}
+
+func TestCSVMapping(t *testing.T) {
+	m, err := ParseMapping("name=Task, start_date=Day,start_time=From,end_time=To")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv := "Task,Day,From,To\nnight shift,12.03.2024 ,22:00,01:00\nbroken,someday,9:00,10:00\n"
+	records, rejected, err := CSV{Mapping: m}.Read(strings.NewReader(csv), berlin)
+	if err != nil || len(records) != 1 || len(rejected) != 1 || rejected[0].Line != 3 {
+		t.Fatalf("Read = %v, %v, %v", records, rejected, err)
+	}
+	// an end time without a date before the start is on the next day
+	if e := records[0].Entry; !e.Start.Equal(at(12, 22, 0)) || !e.End.Equal(at(13, 1, 0)) {
+		t.Errorf("read %s-%s, want %s-%s", e.Start, e.End, at(12, 22, 0), at(13, 1, 0))
+	}
+
+	for _, bad := range []string{"name", "title=Task", "name="} {
+		if _, err := ParseMapping(bad); err == nil {
+			t.Errorf("ParseMapping(%q) succeeded", bad)
+		}
+	}
+	if _, _, err := (CSV{}).Read(strings.NewReader("What,When\nx,y\n"), berlin); err == nil {
+		t.Error("Read without a matching preset succeeded")
+	}
+}
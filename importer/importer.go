@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Record is an entry read from an import file together with where it came from.
+type Record struct {
+	Line  int
+	Entry *models.Entry
+}
+
+// Rejection describes a line that will not be imported.
+type Rejection struct {
+	Line   int
+	Reason string
+}
+
+func (r Rejection) String() string {
+	return fmt.Sprintf("line %d: %s", r.Line, r.Reason)
+}
+
+// Reader parses one import format. Lines that cannot be turned into an entry are rejected
+// and reading continues, only an unusable file as a whole returns an error.
+type Reader interface {
+	Read(r io.Reader, loc *time.Location) ([]Record, []Rejection, error)
+}
+
+var readers = map[string]Reader{}
+
+// Register makes a reader available under name, replacing any previous one.
+func Register(name string, r Reader) {
+	readers[name] = r
+}
+
+func Lookup(name string) (Reader, bool) {
+	r, ok := readers[strings.ToLower(name)]
+	return r, ok
+}
+
+// Formats lists the names of all registered readers.
+func Formats() []string {
+	names := make([]string, 0, len(readers))
+	for name := range readers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("csv", CSV{})
+	Register("json", JSON{})
+	Register("timewarrior", Timewarrior{})
+}
+
+type Result struct {
+	Accepted []Record
+	Rejected []Rejection
+}
+
+// Plan validates the records and drops duplicates of existing entries or of records
+// earlier in the same import. Entries are duplicates when start, end and name match
+// to the second.
+func Plan(existing []*models.Entry, records []Record, rejected []Rejection) Result {
+	seen := make(map[string]bool, len(existing)+len(records))
+	for _, e := range existing {
+		seen[key(e)] = true
+	}
+	result := Result{Rejected: rejected}
+	for _, r := range records {
+		if reason := validate(r.Entry); reason != "" {
+			result.Rejected = append(result.Rejected, Rejection{Line: r.Line, Reason: reason})
+			continue
+		}
+		k := key(r.Entry)
+		if seen[k] {
+			result.Rejected = append(result.Rejected, Rejection{Line: r.Line, Reason: "duplicate of an existing entry"})
+			continue
+		}
+		seen[k] = true
+		result.Accepted = append(result.Accepted, r)
+	}
+	sort.SliceStable(result.Rejected, func(i, j int) bool {
+		return result.Rejected[i].Line < result.Rejected[j].Line
+	})
+	return result
+}
+
+func validate(e *models.Entry) string {
+	switch {
+	case strings.TrimSpace(e.Name) == "":
+		return "empty name"
+	case e.Start.IsZero():
+		return "missing start"
+	case e.End == nil:
+		return "missing end, running entries are not imported"
+	case !e.End.After(e.Start):
+		return "end is not after start"
+	}
+	return ""
+}
+
+func key(e *models.Entry) string {
+	end := ""
+	if e.End != nil {
+		end = e.End.UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+	return e.Start.UTC().Truncate(time.Second).Format(time.RFC3339) + "|" + end + "|" + e.Name
+}
@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+func entry(name string, start time.Time, d time.Duration) *models.Entry {
+	end := start.Add(d)
+	return &models.Entry{Name: name, Start: start, End: &end}
+}
+
+func TestPlan(t *testing.T) {
+	existing := []*models.Entry{entry("review", at(12, 9, 0), time.Hour)}
+	records := []Record{
+		{Line: 2, Entry: entry("review", at(12, 9, 0), time.Hour)},
+		{Line: 3, Entry: entry("review", at(12, 9, 0).Add(400*time.Millisecond), time.Hour)},
+		{Line: 4, Entry: entry("planning", at(12, 9, 0), time.Hour)},
+		{Line: 5, Entry: entry("review", at(12, 9, 0), 2*time.Hour)},
+		{Line: 6, Entry: entry("planning", at(12, 9, 0).In(time.UTC), time.Hour)},
+		{Line: 7, Entry: entry("standup", at(12, 10, 0), 0)},
+	}
+	result := Plan(existing, records, []Rejection{{Line: 1, Reason: "bad row"}})
+
+	accepted := map[int]bool{}
+	for _, r := range result.Accepted {
+		accepted[r.Line] = true
+	}
+	if len(accepted) != 2 || !accepted[4] || !accepted[5] {
+		t.Errorf("accepted lines %v, want 4 and 5", accepted)
+	}
+	want := []Rejection{
+		{1, "bad row"},
+		{2, "duplicate of an existing entry"},
+		{3, "duplicate of an existing entry"},
+		{6, "duplicate of an existing entry"},
+		{7, "end is not after start"},
+	}
+	if len(result.Rejected) != len(want) {
+		t.Fatalf("rejected %v, want %v", result.Rejected, want)
+	}
+	for i, r := range result.Rejected {
+		if r != want[i] {
+			t.Errorf("rejected %v, want %v", r, want[i])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/export"
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// JSON reads documents written by the JSON exporter. Entries are numbered
+// instead of lines since the position in the file is not kept.
+type JSON struct{}
+
+func (JSON) Read(r io.Reader, _ *time.Location) ([]Record, []Rejection, error) {
+	var doc export.Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("could not read json export: %w", err)
+	}
+	if doc.Version > export.SchemaVersion {
+		return nil, nil, fmt.Errorf("export schema version %d is newer than the supported version %d", doc.Version, export.SchemaVersion)
+	}
+	records := make([]Record, 0, len(doc.Entries))
+	for i, e := range doc.Entries {
+		var project *models.Project
+		if e.Project != "" {
+			project = &models.Project{Name: e.Project}
+			if e.Client != "" {
+				project.Client = &models.Client{Name: e.Client}
+			}
+		}
+		records = append(records, Record{
+			Line: i + 1,
+			Entry: &models.Entry{
+				Name:    e.Name,
+				Start:   e.Start,
+				End:     e.End,
+				Content: e.Content,
+				Project: project,
+				Tags:    models.NormalizeTags(e.Tags),
+				Pauses:  pauses(e.Pauses),
+			},
+		})
+	}
+	return records, nil, nil
+}
+
+func pauses(ps []export.Pause) []models.Pause {
+	var pauses []models.Pause
+	for _, p := range ps {
+		pauses = append(pauses, models.Pause{Start: p.Start, End: p.End})
+	}
+	return pauses
+}
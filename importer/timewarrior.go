@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/danielroehrig/timekeeper/models"
+)
+
+// Timewarrior reads the monthly data files of timewarrior, e.g. ~/.timewarrior/data/2026-10.data.
+// Intervals only carry tags, the first one becomes the name and the rest stay tags.
+type Timewarrior struct{}
+
+const timewarriorTimeFormat = "20060102T150405Z"
+
+func (Timewarrior) Read(r io.Reader, _ *time.Location) ([]Record, []Rejection, error) {
+	var records []Record
+	var rejected []Rejection
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		e, err := parseInterval(text)
+		if err != nil {
+			rejected = append(rejected, Rejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		records = append(records, Record{Line: line, Entry: e})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read timewarrior data: %w", err)
+	}
+	return records, rejected, nil
+}
+
+// parseInterval reads "inc <start> [- <end>] [# tag "quoted tag" ...]".
+func parseInterval(text string) (*models.Entry, error) {
+	// tags like "issue #12" are quoted, an annotation follows after another #
+	interval, rest, _ := cutUnquoted(text, '#')
+	tagPart, _, _ := cutUnquoted(rest, '#')
+	words := strings.Fields(interval)
+	if len(words) < 2 || words[0] != "inc" {
+		return nil, fmt.Errorf("not an interval")
+	}
+	start, err := time.Parse(timewarriorTimeFormat, words[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	e := &models.Entry{Start: start}
+	if len(words) == 4 && words[2] == "-" {
+		end, err := time.Parse(timewarriorTimeFormat, words[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+		e.End = &end
+	}
+	tags := splitQuoted(tagPart)
+	if len(tags) > 0 {
+		e.Name = tags[0]
+		e.Tags = models.NormalizeTags(tags[1:])
+	}
+	return e, nil
+}
+
+// cutUnquoted slices s around the first sep outside of double quotes.
+func cutUnquoted(s string, sep rune) (before, after string, found bool) {
+	quoted, escaped := false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+func splitQuoted(s string) []string {
+	var words []string
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+	return words
+}
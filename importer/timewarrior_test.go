@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	start := time.Date(2024, time.March, 12, 8, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+	tests := []struct {
+		line string
+		name string
+		tags []string
+		end  *time.Time
+	}{
+		{"inc 20240312T080000Z - 20240312T093000Z # review backend", "review", []string{"backend"}, &end},
+		{`inc 20240312T080000Z - 20240312T093000Z # "code review" "issue #12" backend`, "code review", []string{"issue #12", "backend"}, &end},
+		{`inc 20240312T080000Z - 20240312T093000Z # review "issue #12" # "annotation with # inside"`, "review", []string{"issue #12"}, &end},
+		{`inc 20240312T080000Z - 20240312T093000Z # "say \"hi\" #3"`, `say "hi" #3`, nil, &end},
+		{"inc 20240312T080000Z # review", "review", nil, nil},
+		{"inc 20240312T080000Z - 20240312T093000Z", "", nil, &end},
+	}
+	for _, tt := range tests {
+		e, err := parseInterval(tt.line)
+		if err != nil {
+			t.Errorf("parseInterval(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if e.Name != tt.name || fmt.Sprint(e.Tags) != fmt.Sprint(tt.tags) {
+			t.Errorf("parseInterval(%q) = %q %v, want %q %v", tt.line, e.Name, e.Tags, tt.name, tt.tags)
+		}
+		if !e.Start.Equal(start) || (e.End == nil) != (tt.end == nil) || e.End != nil && !e.End.Equal(*tt.end) {
+			t.Errorf("parseInterval(%q) = %s-%v", tt.line, e.Start, e.End)
+		}
+	}
+	for _, bad := range []string{"exc monday 9:00", "inc", "inc 2024-03-12 # review"} {
+		if _, err := parseInterval(bad); err == nil {
+			t.Errorf("parseInterval(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestTimewarriorRejectsUntagged(t *testing.T) {
+	data := "inc 20240312T080000Z - 20240312T093000Z # review\n" +
+		"\n" +
+		"inc 20240312T100000Z - 20240312T110000Z\n" +
+		"inc 20240312T120000Z # running\n"
+	records, rejected, err := Timewarrior{}.Read(strings.NewReader(data), time.UTC)
+	if err != nil || len(rejected) != 0 || len(records) != 3 {
+		t.Fatalf("Read = %d records, %v, %v", len(records), rejected, err)
+	}
+	result := Plan(nil, records, rejected)
+	if len(result.Accepted) != 1 || result.Accepted[0].Entry.Name != "review" {
+		t.Errorf("accepted %v, want only review", result.Accepted)
+	}
+	if len(result.Rejected) != 2 || result.Rejected[0].Line != 3 || result.Rejected[0].Reason != "empty name" || result.Rejected[1].Line != 4 {
+		t.Errorf("rejected %v, want the untagged line 3 and the running line 4", result.Rejected)
+	}
+}
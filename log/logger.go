@@ -1,6 +1,13 @@
 package log
 
-import "log"
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
 
 type Logger interface {
 	Debugf(format string, args ...interface{})
@@ -9,8 +16,11 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 }
 
+// Impl writes through log/slog. Errors are logged like everything else,
+// it is up to the caller to stop the program if it can't go on.
 type Impl struct {
-	loglevel byte
+	logger *slog.Logger
+	level  *slog.LevelVar
 }
 
 const (
@@ -20,7 +30,66 @@ const (
 	LevelError
 )
 
-var std = Impl{loglevel: LevelInfo}
+// Options configure where and how logs are written.
+type Options struct {
+	Path     string // log file, empty logs to stderr
+	Level    string // debug, info, warn or error
+	Format   string // text or json
+	MaxSize  int64  // size in bytes after which the file is rotated, 0 never rotates
+	MaxFiles int    // number of rotated files kept next to the log file
+}
+
+var std = newImpl(os.Stderr, "text")
+
+func newImpl(w io.Writer, format string) *Impl {
+	level := &slog.LevelVar{}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if format == "json" {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return &Impl{logger: slog.New(h), level: level}
+}
+
+// Setup replaces the default logger. The returned closer closes the log file.
+// Other packages logging through the standard library end up in the same place.
+func Setup(o Options) (io.Closer, error) {
+	level, err := ParseLevel(o.Level)
+	if err != nil {
+		return nil, err
+	}
+	if o.Format != "" && o.Format != "text" && o.Format != "json" {
+		return nil, fmt.Errorf("unknown log format %q, use text or json", o.Format)
+	}
+	var w io.WriteCloser = nopCloser{os.Stderr}
+	if o.Path != "" {
+		w, err = openRotating(o.Path, o.MaxSize, o.MaxFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+	std = newImpl(w, o.Format)
+	SetLogLevel(level)
+	slog.SetDefault(std.logger)
+	return w, nil
+}
+
+// ParseLevel reads a level name, an empty name is info.
+func ParseLevel(s string) (byte, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, use debug, info, warn or error", s)
+}
 
 func Debugf(format string, args ...interface{}) {
 	std.Debugf(format, args...)
@@ -39,27 +108,40 @@ func Errorf(format string, args ...interface{}) {
 }
 
 func SetLogLevel(level byte) {
-	std.loglevel = level
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	if int(level) < len(levels) {
+		std.level.Set(levels[level])
+	}
 }
 
 func (l *Impl) Debugf(format string, args ...interface{}) {
-	if l.loglevel == LevelDebug {
-		log.Printf("DEBUG: "+format, args...)
-	}
+	l.log(slog.LevelDebug, format, args...)
 }
 
 func (l *Impl) Infof(format string, args ...interface{}) {
-	if l.loglevel <= LevelInfo {
-		log.Printf("INFO: "+format, args...)
-	}
+	l.log(slog.LevelInfo, format, args...)
 }
 
 func (l *Impl) Warnf(format string, args ...interface{}) {
-	if l.loglevel <= LevelWarn {
-		log.Printf("WARN: "+format, args...)
-	}
+	l.log(slog.LevelWarn, format, args...)
 }
 
 func (l *Impl) Errorf(format string, args ...interface{}) {
-	log.Fatalf("ERROF: "+format, args...)
+	l.log(slog.LevelError, format, args...)
+}
+
+func (l *Impl) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	l.logger.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
 }
@@ -1,67 +1,83 @@
 package main
 
 import (
-	tea "github.com/charmbracelet/bubbletea"
+	"errors"
+	"fmt"
 	"github.com/danielroehrig/timekeeper/app"
+	"github.com/danielroehrig/timekeeper/backup"
+	"github.com/danielroehrig/timekeeper/cli"
+	"github.com/danielroehrig/timekeeper/config"
 	dbaccess "github.com/danielroehrig/timekeeper/db"
 	"github.com/danielroehrig/timekeeper/log"
-	"github.com/ostafen/clover/v2"
-	"github.com/spf13/viper"
 	"os"
-	"path"
-	"path/filepath"
-	"strings"
+	"time"
 )
 
-var db *clover.DB
+var db dbaccess.EntryStore
 
 func main() {
-	// set up loggin
-	switch strings.ToLower(os.Getenv("LOGLEVEL")) {
-	case "debug":
-		log.SetLogLevel(log.LevelDebug)
-	case "info":
-		log.SetLogLevel(log.LevelInfo)
-	case "warn":
-		log.SetLogLevel(log.LevelWarn)
-	case "error":
-		log.SetLogLevel(log.LevelError)
+	os.Exit(run())
+}
+
+func run() int {
+	// load configs
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
 	}
-	f, err := tea.LogToFile(path.Join(os.TempDir(), "timekeeper.log"), "")
+
+	// entries are shown and typed in the configured zone
+	time.Local = cfg.Location()
+
+	// set up logging
+	f, err := log.Setup(cfg.LogOptions())
 	if err != nil {
-		log.Errorf("Failed to open log file: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
 	}
 	defer f.Close()
 
-	// log configs
-	loadConfig()
+	// subcommands run without the TUI
+	args := os.Args[1:]
+	if len(args) > 0 && !cli.NeedsDatabase(args[0]) {
+		return cli.Run(nil, cfg, args, os.Stdin, os.Stdout, os.Stderr)
+	}
 
 	// set up database access
-	db = dbaccess.OpenDatabase()
-	defer dbaccess.CloseDatabase(db)
-
-	// run the app
-	if err := app.Run(db); err != nil {
-		log.Errorf("Error running program: %v", err)
+	db, err = dbaccess.Open(cfg.Store, cfg.DatabasePath)
+	var warnings []error
+	if errors.As(err, new(*dbaccess.MigrationError)) {
+		// the entries that could not be upgraded keep their old schema, the rest is fine
+		log.Errorf("%v", err)
+		fmt.Fprintln(os.Stderr, "warning:", err)
+		warnings = append(warnings, err)
+	} else if err != nil {
+		log.Errorf("%v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
 	}
-}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Errorf("%v", err)
+		}
+	}()
 
-func loadConfig() {
-	log.Infof("Loading configuration...")
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		log.Errorf("could not find config dir. Aborting. %s", err)
+	if _, err := backup.Scheduled(db, cfg.BackupOptions(), time.Now()); err != nil {
+		log.Errorf("backup failed: %v", err)
+		fmt.Fprintln(os.Stderr, "warning: backup failed:", err)
+		warnings = append(warnings, fmt.Errorf("backup failed: %w", err))
 	}
-	configFile := filepath.Join(configDir, "timekeeper", "config.yml")
-	err = os.Mkdir(filepath.Dir(configFile), 0755)
-	if err != nil && !os.IsExist(err) {
-		log.Errorf("could not create config folder %v", err)
+
+	if len(args) > 0 {
+		return cli.Run(db, cfg, args, os.Stdin, os.Stdout, os.Stderr)
 	}
-	viper.SetConfigFile(configFile)
-	viper.SetDefault("someValue", "foobar")
-	viper.Set("foo", "bar")
-	err = viper.WriteConfig()
-	if err != nil {
-		log.Errorf("could not write to config %v", err)
+
+	// run the app
+	if err := app.Run(db, cfg, warnings...); err != nil {
+		log.Errorf("Error running program: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
 	}
+	return cli.ExitOK
 }
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -10,8 +13,87 @@ type Entry struct {
 	End      *time.Time
 	Name     string
 	Content  string
+	Project  *Project
+	Tags     []string
+	// Pauses are sorted by start, see Segments.
+	Pauses []Pause
 }
 
 func (e *Entry) FilterValue() string {
-	return e.Name
+	value := e.Name
+	if e.Project != nil {
+		value += " @" + e.Project.String()
+	}
+	if tags := e.TagString(); tags != "" {
+		value += " " + tags
+	}
+	return value
+}
+
+// TagString renders the tags the way they are typed, e.g. "#meeting #billable".
+func (e *Entry) TagString() string {
+	tags := make([]string, 0, len(e.Tags))
+	for _, t := range e.Tags {
+		tags = append(tags, "#"+t)
+	}
+	return strings.Join(tags, " ")
+}
+
+// ValidateTimes checks that an entry does not end before it starts and neither
+// starts nor ends in the future. A nil end marks a running entry.
+func ValidateTimes(start time.Time, end *time.Time, now time.Time) error {
+	if start.IsZero() {
+		return errors.New("start is missing")
+	}
+	if start.After(now) {
+		return errors.New("start is in the future")
+	}
+	if end == nil {
+		return nil
+	}
+	if !end.After(start) {
+		return errors.New("end has to be after start")
+	}
+	if end.After(now) {
+		return errors.New("end is in the future")
+	}
+	return nil
+}
+
+// Overlap finds the finished entry among entries that e would overlap from
+// start to end, a nil end meaning now. An entry that started before and ends
+// within that time is returned to be cut short at start, any other overlap is
+// an error. Running entries are left to the caller.
+func Overlap(entries []*Entry, e *Entry, start time.Time, end *time.Time, now time.Time) (*Entry, error) {
+	until := now
+	if end != nil {
+		until = *end
+	}
+	var previous *Entry
+	for _, o := range entries {
+		if o == e || o.End == nil || !o.Start.Before(until) || !o.End.After(start) {
+			continue
+		}
+		switch {
+		case previous == nil && o.Start.Before(start) && !o.End.After(until):
+			previous = o
+		case o.Start.Before(start):
+			return nil, fmt.Errorf("it lies within %s, edit that first", o.Name)
+		case o.End.After(until):
+			return nil, fmt.Errorf("it overlaps the start of %s, edit that first", o.Name)
+		default:
+			return nil, fmt.Errorf("%s would be covered completely, edit or delete it first", o.Name)
+		}
+	}
+	return previous, nil
+}
+
+// ContinueAt returns a new entry starting at start with the name, project and tags of e.
+func (e *Entry) ContinueAt(start time.Time) *Entry {
+	return &Entry{
+		Start:   start,
+		Name:    e.Name,
+		Project: e.Project,
+		Tags:    append([]string(nil), e.Tags...),
+	}
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParseInput splits a typed task like "review PR @acme/website #review #billable"
+// into the plain name, the project and the tags.
+func ParseInput(value string) (string, *Project, []string) {
+	var project *Project
+	var tags []string
+	words := strings.Fields(value)
+	name := make([]string, 0, len(words))
+	for _, w := range words {
+		switch {
+		case strings.HasPrefix(w, "@") && len(w) > 1:
+			project = ParseProject(w[1:])
+		case strings.HasPrefix(w, "#") && len(w) > 1:
+			tags = append(tags, w[1:])
+		default:
+			name = append(name, w)
+		}
+	}
+	return strings.Join(name, " "), project, NormalizeTags(tags)
+}
+
+// NormalizeTags lowercases tags, strips a leading # and removes empty and duplicate ones.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "#"))
+		if t == "" || slices.Contains(normalized, t) {
+			continue
+		}
+		normalized = append(normalized, t)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
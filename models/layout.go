@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layout holds the Go layouts used to show and type dates and times.
+type Layout struct {
+	Date string
+	Time string
+}
+
+var DefaultLayout = Layout{Date: "2006-01-02", Time: "15:04"}
+
+func (l Layout) DateTime() string {
+	return l.Date + " " + l.Time
+}
+
+// Validate makes sure both layouts survive formatting and parsing a time.
+func (l Layout) Validate() error {
+	ref := time.Date(2006, time.January, 2, 15, 4, 0, 0, time.Local)
+	for _, layout := range []string{l.Date, l.Time} {
+		if strings.TrimSpace(layout) == "" {
+			return fmt.Errorf("date and time layouts must not be empty")
+		}
+	}
+	parsed, err := time.ParseInLocation(l.DateTime(), ref.Format(l.DateTime()), time.Local)
+	if err != nil || !parsed.Equal(ref) {
+		return fmt.Errorf("layout %q does not keep date, hour and minute, see https://pkg.go.dev/time#Layout", l.DateTime())
+	}
+	return nil
+}
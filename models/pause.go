@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Pause interrupts an entry. End is nil while the entry is paused.
+type Pause struct {
+	Start time.Time
+	End   *time.Time
+}
+
+// Segment is a span of time in which an entry was active.
+type Segment struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Paused reports whether e is running and paused right now.
+func (e *Entry) Paused() bool {
+	return e.End == nil && len(e.Pauses) > 0 && e.Pauses[len(e.Pauses)-1].End == nil
+}
+
+// Pause interrupts the running entry at at.
+func (e *Entry) Pause(at time.Time) error {
+	switch {
+	case e.End != nil:
+		return errors.New("only a running task can be paused")
+	case e.Paused():
+		return errors.New("task is paused already")
+	case at.Before(e.Start):
+		return errors.New("pause can't start before the task")
+	}
+	e.Pauses = append(e.Pauses, Pause{Start: at})
+	return nil
+}
+
+// Resume ends the pause of the entry at at.
+func (e *Entry) Resume(at time.Time) error {
+	if !e.Paused() {
+		return errors.New("task is not paused")
+	}
+	p := &e.Pauses[len(e.Pauses)-1]
+	if at.Before(p.Start) {
+		at = p.Start
+	}
+	p.End = &at
+	return nil
+}
+
+// Stop ends the entry at end. A paused entry ends when its pause began, the
+// open pause is dropped.
+func (e *Entry) Stop(end time.Time) {
+	if e.Paused() {
+		last := len(e.Pauses) - 1
+		if e.Pauses[last].Start.Before(end) {
+			end = e.Pauses[last].Start
+		}
+		e.Pauses = e.Pauses[:last]
+	}
+	e.End = &end
+}
+
+// Segments splits the entry at its pauses into the spans it was active. A
+// running entry counts until now, a paused one until its pause began.
+func (e *Entry) Segments(now time.Time) []Segment {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	var segments []Segment
+	start := e.Start
+	for _, p := range e.Pauses {
+		if !p.Start.Before(end) {
+			break
+		}
+		if p.Start.After(start) {
+			segments = append(segments, Segment{Start: start, End: p.Start})
+		}
+		if p.End == nil {
+			return segments
+		}
+		if p.End.After(start) {
+			start = *p.End
+		}
+	}
+	if end.After(start) {
+		segments = append(segments, Segment{Start: start, End: end})
+	}
+	return segments
+}
+
+// Duration returns the time the entry was active, pauses left out.
+func (e *Entry) Duration(now time.Time) time.Duration {
+	return e.DurationBetween(time.Time{}, time.Time{}, now)
+}
+
+// DurationBetween returns the time the entry was active within [from, to).
+// A zero from or to leaves that side of the range open.
+func (e *Entry) DurationBetween(from, to, now time.Time) time.Duration {
+	var d time.Duration
+	for _, s := range e.Segments(now) {
+		if !from.IsZero() && s.Start.Before(from) {
+			s.Start = from
+		}
+		if !to.IsZero() && s.End.After(to) {
+			s.End = to
+		}
+		if s.End.After(s.Start) {
+			d += s.End.Sub(s.Start)
+		}
+	}
+	return d
+}
+
+// PausedBetween returns how long the entry was paused within [from, now).
+func (e *Entry) PausedBetween(from, now time.Time) time.Duration {
+	var d time.Duration
+	for _, p := range e.Pauses {
+		start, end := p.Start, now
+		if p.End != nil && p.End.Before(now) {
+			end = *p.End
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			d += end.Sub(start)
+		}
+	}
+	return d
+}
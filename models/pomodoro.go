@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// BreakTag marks the break entries recorded by the Pomodoro mode.
+const BreakTag = "break"
+
+const (
+	ShortBreakName = "Short break"
+	LongBreakName  = "Long break"
+)
+
+// Pomodoro holds the interval lengths of the Pomodoro mode. Every
+// LongBreakEvery-th work interval of a day is followed by a long break.
+type Pomodoro struct {
+	Work           time.Duration
+	ShortBreak     time.Duration
+	LongBreak      time.Duration
+	LongBreakEvery int
+}
+
+func (p Pomodoro) Validate() error {
+	if p.Work <= 0 || p.ShortBreak <= 0 || p.LongBreak <= 0 {
+		return errors.New("pomodoro work, short_break and long_break must be positive")
+	}
+	if p.LongBreakEvery < 1 {
+		return errors.New("pomodoro long_break_every must be at least 1")
+	}
+	return nil
+}
+
+// Break returns the break entry following the n-th work interval of the day, started at start.
+func (p Pomodoro) Break(n int, start time.Time) *Entry {
+	name := ShortBreakName
+	if n > 0 && n%p.LongBreakEvery == 0 {
+		name = LongBreakName
+	}
+	return &Entry{Start: start, Name: name, Tags: []string{BreakTag}}
+}
+
+// Length returns how long the running entry e lasts in Pomodoro mode.
+func (p Pomodoro) Length(e *Entry) time.Duration {
+	switch {
+	case !e.IsBreak():
+		return p.Work
+	case e.Name == LongBreakName:
+		return p.LongBreak
+	default:
+		return p.ShortBreak
+	}
+}
+
+// IsBreak reports whether the entry is a break recorded by the Pomodoro mode.
+func (e *Entry) IsBreak() bool {
+	return slices.Contains(e.Tags, BreakTag)
+}
+
+// CountPomodoros returns the number of work intervals finished on the day of
+// now, each of them is followed by a break entry. Only finished breaks are
+// counted, a running one is counted by whoever runs it.
+func CountPomodoros(entries []*Entry, now time.Time) int {
+	year, month, day := now.Date()
+	n := 0
+	for _, e := range entries {
+		y, m, d := e.Start.In(now.Location()).Date()
+		if e.IsBreak() && e.End != nil && y == year && m == month && d == day {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package models
+
+import "strings"
+
+type Client struct {
+	ObjectId string
+	Name     string
+}
+
+type Project struct {
+	ObjectId string
+	Name     string
+	Client   *Client
+}
+
+// ParseProject reads a project written as "client/project" or just "project".
+// The returned project is not persisted yet and has no ObjectId.
+func ParseProject(s string) *Project {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	clientName, projectName, found := strings.Cut(s, "/")
+	if !found {
+		return &Project{Name: s}
+	}
+	clientName, projectName = strings.TrimSpace(clientName), strings.TrimSpace(projectName)
+	if projectName == "" {
+		return nil
+	}
+	if clientName == "" {
+		return &Project{Name: projectName}
+	}
+	return &Project{Name: projectName, Client: &Client{Name: clientName}}
+}
+
+func (p *Project) String() string {
+	if p == nil {
+		return ""
+	}
+	if p.Client != nil {
+		return p.Client.Name + "/" + p.Name
+	}
+	return p.Name
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// Rounding describes how durations are rounded for display and reports.
+// A zero interval keeps durations as they are.
+type Rounding struct {
+	Interval time.Duration
+	Mode     string
+}
+
+func (r Rounding) Round(d time.Duration) time.Duration {
+	if r.Interval <= 0 {
+		return d
+	}
+	switch r.Mode {
+	case RoundUp:
+		if rest := d % r.Interval; rest != 0 {
+			return d - rest + r.Interval
+		}
+		return d
+	case RoundDown:
+		return d.Truncate(r.Interval)
+	default:
+		return d.Round(r.Interval)
+	}
+}
+
+func (r Rounding) Validate() error {
+	if r.Interval < 0 {
+		return fmt.Errorf("rounding interval must not be negative")
+	}
+	switch r.Mode {
+	case RoundNearest, RoundUp, RoundDown:
+		return nil
+	}
+	return fmt.Errorf("unknown rounding mode %q, use %s, %s or %s", r.Mode, RoundNearest, RoundUp, RoundDown)
+}
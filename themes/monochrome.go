@@ -0,0 +1,60 @@
+package themes
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Monochrome uses no colors at all, emphasis comes from bold text and border shapes.
+// It is used whenever NO_COLOR is set.
+type Monochrome struct{}
+
+func NewMonochrome() Monochrome {
+	return Monochrome{}
+}
+
+// NoColor reports whether the user asked for no colors, see https://no-color.org.
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+func (Monochrome) Background() lipgloss.Color {
+	return ""
+}
+
+func (Monochrome) Foreground() lipgloss.Color {
+	return ""
+}
+
+func (Monochrome) Accent() lipgloss.Color {
+	return ""
+}
+
+func (Monochrome) AltAccent() lipgloss.Color {
+	return ""
+}
+
+func (Monochrome) Subtext() lipgloss.Color {
+	return ""
+}
+
+func (Monochrome) NormalStyle() lipgloss.Style {
+	return lipgloss.NewStyle()
+}
+
+func (Monochrome) SubtextStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Faint(true)
+}
+
+func (Monochrome) WidgetStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true)
+}
+
+func (Monochrome) ActiveWidgetStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Border(lipgloss.ThickBorder(), true)
+}
+
+func (Monochrome) AccentStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Bold(true)
+}
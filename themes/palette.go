@@ -0,0 +1,133 @@
+package themes
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Colors describe a theme in the config. Colors are hex values like #1f2335 or
+// ANSI color numbers, missing ones are taken from the Base theme.
+type Colors struct {
+	Base       string `mapstructure:"base"`
+	Background string `mapstructure:"background"`
+	Foreground string `mapstructure:"foreground"`
+	Accent     string `mapstructure:"accent"`
+	AltAccent  string `mapstructure:"alt_accent"`
+	Subtext    string `mapstructure:"subtext"`
+}
+
+var builtins = map[string]Colors{
+	"tokyonight-day":   {Background: "#e1e2e7", Foreground: "#3760bf", Accent: "#9854f1", AltAccent: "#f52a65", Subtext: "#848cb5"},
+	"catppuccin-mocha": {Background: "#1e1e2e", Foreground: "#cdd6f4", Accent: "#cba6f7", AltAccent: "#f38ba8", Subtext: "#a6adc8"},
+	"catppuccin-latte": {Background: "#eff1f5", Foreground: "#4c4f69", Accent: "#8839ef", AltAccent: "#d20f39", Subtext: "#6c6f85"},
+	"gruvbox-dark":     {Background: "#282828", Foreground: "#ebdbb2", Accent: "#fabd2f", AltAccent: "#fb4934", Subtext: "#a89984"},
+	"gruvbox-light":    {Background: "#fbf1c7", Foreground: "#3c3836", Accent: "#b57614", AltAccent: "#9d0006", Subtext: "#7c6f64"},
+	"solarized-dark":   {Background: "#002b36", Foreground: "#839496", Accent: "#268bd2", AltAccent: "#dc322f", Subtext: "#586e75"},
+	"solarized-light":  {Background: "#fdf6e3", Foreground: "#657b83", Accent: "#268bd2", AltAccent: "#dc322f", Subtext: "#93a1a1"},
+}
+
+// Palette is a theme made of five colors, styled like TokyoNight.
+type Palette struct {
+	background lipgloss.Color
+	foreground lipgloss.Color
+	accent     lipgloss.Color
+	altAccent  lipgloss.Color
+	subtext    lipgloss.Color
+}
+
+func NewPalette(c Colors) Palette {
+	return Palette{
+		background: lipgloss.Color(c.Background),
+		foreground: lipgloss.Color(c.Foreground),
+		accent:     lipgloss.Color(c.Accent),
+		altAccent:  lipgloss.Color(c.AltAccent),
+		subtext:    lipgloss.Color(c.Subtext),
+	}
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// BaseName is the name of the theme missing colors are taken from.
+func (c Colors) BaseName() string {
+	if c.Base == "" {
+		return "tokyonight"
+	}
+	return strings.ToLower(c.Base)
+}
+
+// FromBase builds a user defined theme, filling missing colors from b.
+func FromBase(c Colors, b Theme) (Palette, error) {
+	fields := []struct {
+		name  string
+		value *string
+		base  lipgloss.Color
+	}{
+		{"background", &c.Background, b.Background()},
+		{"foreground", &c.Foreground, b.Foreground()},
+		{"accent", &c.Accent, b.Accent()},
+		{"alt_accent", &c.AltAccent, b.AltAccent()},
+		{"subtext", &c.Subtext, b.Subtext()},
+	}
+	for _, f := range fields {
+		if *f.value == "" {
+			*f.value = string(f.base)
+			continue
+		}
+		if !validColor(*f.value) {
+			return Palette{}, fmt.Errorf("%s: invalid color %q, use #rrggbb or an ANSI number", f.name, *f.value)
+		}
+	}
+	return NewPalette(c), nil
+}
+
+func validColor(c string) bool {
+	if hexColor.MatchString(c) {
+		return true
+	}
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func (p Palette) Background() lipgloss.Color {
+	return p.background
+}
+
+func (p Palette) Foreground() lipgloss.Color {
+	return p.foreground
+}
+
+func (p Palette) Accent() lipgloss.Color {
+	return p.accent
+}
+
+func (p Palette) AltAccent() lipgloss.Color {
+	return p.altAccent
+}
+
+func (p Palette) Subtext() lipgloss.Color {
+	return p.subtext
+}
+
+func (p Palette) NormalStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(p.foreground).Bold(false)
+}
+
+func (p Palette) SubtextStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(p.subtext).Bold(false)
+}
+
+func (p Palette) WidgetStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(p.foreground)
+}
+
+func (p Palette) ActiveWidgetStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(p.accent)
+}
+
+func (p Palette) AccentStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(p.accent).Bold(true)
+}
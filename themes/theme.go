@@ -1,6 +1,11 @@
 package themes
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 type Theme interface {
 	Background() lipgloss.Color
@@ -14,3 +19,51 @@ type Theme interface {
 	ActiveWidgetStyle() lipgloss.Style
 	AccentStyle() lipgloss.Style
 }
+
+// ChangedMsg is sent to every component when the theme is switched at runtime.
+type ChangedMsg struct {
+	Name  string
+	Theme Theme
+}
+
+var registry = map[string]Theme{}
+
+// Register makes a theme available under name, replacing a theme of the same name.
+func Register(name string, t Theme) {
+	registry[strings.ToLower(name)] = t
+}
+
+// ByName returns the registered theme with the given name.
+func ByName(name string) (Theme, bool) {
+	t, ok := registry[strings.ToLower(name)]
+	return t, ok
+}
+
+// Names lists the registered themes sorted by name.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Next returns the name of the theme after name, wrapping around.
+func Next(name string) string {
+	names := Names()
+	for i, n := range names {
+		if n == strings.ToLower(name) {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+func init() {
+	Register("tokyonight", NewTokyoNight())
+	Register("monochrome", NewMonochrome())
+	for name, c := range builtins {
+		Register(name, NewPalette(c))
+	}
+}
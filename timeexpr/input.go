@@ -0,0 +1,94 @@
+package timeexpr
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Span is a time expression found at the end of a task input. End is nil if
+// only a start was given.
+type Span struct {
+	Start time.Time
+	End   *time.Time
+}
+
+// dayWords start expressions that are meant as times, an ambiguous time
+// starting with one of them is reported instead of kept in the name.
+var dayWords = map[string]bool{"today": true, "yesterday": true, "tomorrow": true, "last": true, "this": true, "next": true}
+
+// keywords introduce a time at the end of a task input, like "standup at 9am".
+var keywords = map[string]bool{"at": true, "since": true}
+
+// Trailing looks for a range or a start at the end of a task input like
+// "standup #meeting yesterday 14:00-15:30". Words for projects and tags are
+// skipped, at least one word is left for the name. It returns the input
+// without the expression and nil if there is none. A day alone is part of the
+// name, like in "plan tomorrow", unless it follows "at" or "since".
+func (p Parser) Trailing(input string, now time.Time) (string, *Span, error) {
+	words := strings.Fields(input)
+	var plain []int
+	for i, w := range words {
+		if !strings.HasPrefix(w, "@") && !strings.HasPrefix(w, "#") {
+			plain = append(plain, i)
+		}
+	}
+	for k := 1; k < len(plain); k++ {
+		used := make(map[int]bool, len(plain)-k)
+		expr := make([]string, 0, len(plain)-k)
+		for _, i := range plain[k:] {
+			used[i] = true
+			expr = append(expr, words[i])
+		}
+		keyword := keywords[strings.ToLower(expr[0])]
+		if keyword {
+			if len(expr) == 1 {
+				continue
+			}
+			expr = expr[1:]
+		}
+		var rest []string
+		for i, w := range words {
+			if !used[i] {
+				rest = append(rest, w)
+			}
+		}
+		v := strings.Join(expr, " ")
+		if start, end, err := p.Range(v, now, now); err == nil {
+			return strings.Join(rest, " "), &Span{Start: start, End: &end}, nil
+		}
+		start, err := p.Time(v, now, now)
+		if err == nil {
+			return strings.Join(rest, " "), &Span{Start: start}, nil
+		}
+		// the longest expression decides, "friday 9am" is not read as 9am today
+		var amb *AmbiguousError
+		if errors.As(err, &amb) && (keyword || p.dayWithClock(expr)) {
+			return input, nil, err
+		}
+	}
+	return input, nil, nil
+}
+
+// dayWithClock reports whether words start with a day and end with a time of
+// day or a range of them, like "friday 9am" or "friday 9am-10am".
+func (p Parser) dayWithClock(words []string) bool {
+	first := strings.ToLower(words[0])
+	if _, weekday := weekdays[first]; len(words) < 2 || !dayWords[first] && !weekday {
+		return false
+	}
+	last := words[len(words)-1]
+	if i := strings.LastIndex(last, "-"); i >= 0 {
+		last = last[i+1:]
+	}
+	candidates := []string{last}
+	if len(words) > 2 {
+		candidates = append(candidates, words[len(words)-2]+" "+last)
+	}
+	for _, c := range candidates {
+		if _, _, _, err := p.clock(c); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+// Package timeexpr reads the times typed into timekeeper, from plain clock
+// times to expressions like "yesterday 14:00-15:30", "2h ago" or "last friday 9am".
+package timeexpr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser resolves time expressions. The zero value reads times in the local
+// time zone with weeks starting on Sunday.
+type Parser struct {
+	// Location is the time zone typed times are in, nil for the local one.
+	Location *time.Location
+	// FirstWeekday starts the weeks "this friday" and "last friday" refer to.
+	FirstWeekday time.Weekday
+	// DateLayout and TimeLayout are the configured Go layouts, they are read
+	// besides ISO dates and 24 hour times.
+	DateLayout string
+	TimeLayout string
+}
+
+// AmbiguousError is returned for input that could mean more than one time.
+type AmbiguousError struct {
+	Input string
+	Hint  string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q is ambiguous, %s", e.Input, e.Hint)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var (
+	twelveHour = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)$`)
+	digits     = regexp.MustCompile(`^\d+$`)
+)
+
+func (p Parser) loc() *time.Location {
+	if p.Location == nil {
+		return time.Local
+	}
+	return p.Location
+}
+
+// In returns t in the time zone typed times are read in, so shown times can be typed back.
+func (p Parser) In(t time.Time) time.Time {
+	return t.In(p.loc())
+}
+
+// Time reads a point in time. A time of day without a day falls on day,
+// relative words like "yesterday" or "2h ago" count from now.
+func (p Parser) Time(s string, day, now time.Time) (time.Time, error) {
+	t, hasClock, err := p.point(s, day, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !hasClock {
+		v := strings.TrimSpace(s)
+		return time.Time{}, &AmbiguousError{Input: v, Hint: fmt.Sprintf("add a time of day like %q", v+" 14:00")}
+	}
+	return t, nil
+}
+
+// Day reads a day or a point in time, a day alone means its midnight. A plain
+// duration like 72h counts back from now.
+func (p Parser) Day(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+		return now.Add(-d), nil
+	}
+	t, _, err := p.point(s, now, now)
+	return t, err
+}
+
+// Range reads a start and an end like "yesterday 14:00-15:30" or "9am for 1h30m".
+// An end without a day is on the day of the start, or the day after if it
+// would end before it started, like in "22:00-01:00".
+func (p Parser) Range(s string, day, now time.Time) (time.Time, time.Time, error) {
+	v := strings.TrimSpace(s)
+	if i := strings.LastIndex(strings.ToLower(v), " for "); i >= 0 {
+		start, err := p.Time(v[:i], day, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		d, err := p.Duration(v[i+len(" for "):])
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return start, start.Add(d), nil
+	}
+	var firstErr error
+	for i := strings.LastIndex(v, "-"); i > 0; i = strings.LastIndex(v[:i], "-") {
+		start, end, err := p.split(v[:i], v[i+1:], day, now)
+		if err == nil {
+			return start, end, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return time.Time{}, time.Time{}, firstErr
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%q is no range like %q", v, "yesterday 14:00-15:30")
+}
+
+func (p Parser) split(left, right string, day, now time.Time) (time.Time, time.Time, error) {
+	if strings.TrimSpace(left) == "" || strings.TrimSpace(right) == "" {
+		return time.Time{}, time.Time{}, errors.New("start or end is missing")
+	}
+	start, err := p.Time(left, day, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := p.End(right, start, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end.After(start) {
+		return start, end, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%q ends before it starts", strings.TrimSpace(left)+"-"+strings.TrimSpace(right))
+}
+
+// End reads the end of something that began at start. An end without a day is
+// on the day of the start, or the day after if it would end before it started.
+// An end before start is returned as it is.
+func (p Parser) End(s string, start, now time.Time) (time.Time, error) {
+	end, err := p.Time(s, start, now)
+	if err != nil || end.After(start) {
+		return end, err
+	}
+	// a time of day before the start is on the next day
+	next, err := p.Time(s, start.AddDate(0, 0, 1), now)
+	if err == nil && next.After(start) && !next.Equal(end) {
+		return next, nil
+	}
+	return end, nil
+}
+
+// Duration reads lengths like 1h30m, "1h 30m", 90m, 1.5h or 1:30.
+func (p Parser) Duration(s string) (time.Duration, error) {
+	v := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "")
+	if v == "" {
+		return 0, errors.New("duration is missing")
+	}
+	if h, m, ok := strings.Cut(v, ":"); ok {
+		hours, herr := strconv.Atoi(h)
+		minutes, merr := strconv.Atoi(m)
+		if herr == nil && merr == nil && hours >= 0 && minutes >= 0 && minutes < 60 {
+			return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+		}
+	}
+	if digits.MatchString(v) {
+		return 0, &AmbiguousError{Input: strings.TrimSpace(s), Hint: fmt.Sprintf("add a unit like %sm or %sh", v, v)}
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is no duration like 1h30m, 90m or 1:30", strings.TrimSpace(s))
+	}
+	return d, nil
+}
+
+// Correction reads a change of t: a signed duration like -10m or +5m moves t,
+// anything else is read like Time on the day of now.
+func (p Parser) Correction(s string, t, now time.Time) (time.Time, error) {
+	v := strings.TrimSpace(s)
+	if strings.HasPrefix(v, "-") || strings.HasPrefix(v, "+") {
+		d, err := time.ParseDuration(strings.ReplaceAll(v, " ", ""))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is no duration like -10m or +1h5m", v)
+		}
+		return t.Add(d), nil
+	}
+	return p.Time(v, now, now)
+}
+
+// point reads a time and reports whether it had a time of day. Days alone are at midnight.
+func (p Parser) point(s string, day, now time.Time) (time.Time, bool, error) {
+	loc := p.loc()
+	now, day = now.In(loc), day.In(loc)
+	raw := strings.Fields(s)
+	if len(raw) == 0 {
+		return time.Time{}, false, errors.New("time is missing")
+	}
+	v := strings.Join(raw, " ")
+	for _, layout := range p.dateTimeLayouts() {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, true, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true, nil
+	}
+	lower := strings.ToLower(v)
+	switch {
+	case lower == "now":
+		return now, true, nil
+	case strings.HasSuffix(lower, " ago"):
+		d, err := p.Duration(lower[:len(lower)-len(" ago")])
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return now.Add(-d), true, nil
+	case strings.HasPrefix(lower, "in "):
+		d, err := p.Duration(lower[len("in "):])
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return now.Add(d), true, nil
+	}
+	if _, err := time.ParseDuration(lower); err == nil {
+		return time.Time{}, false, &AmbiguousError{Input: v, Hint: fmt.Sprintf("write %q for a time in the past", v+" ago")}
+	}
+
+	date, n, err := p.date(raw, now)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if n == len(raw) {
+		return date, false, nil
+	}
+	h, m, sec, err := p.clock(strings.Join(raw[n:], " "))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if n == 0 {
+		date = day
+	}
+	y, mo, d := date.Date()
+	return time.Date(y, mo, d, h, m, sec, 0, loc), true, nil
+}
+
+// date reads the day at the start of words and returns it with the number of words used.
+func (p Parser) date(words []string, now time.Time) (time.Time, int, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	first := strings.ToLower(words[0])
+	switch first {
+	case "today":
+		return today, 1, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), 1, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), 1, nil
+	case "last", "this", "next":
+		if len(words) < 2 {
+			return time.Time{}, 0, fmt.Errorf("%q needs a weekday like %q", first, first+" friday")
+		}
+		wd, ok := weekdays[strings.ToLower(words[1])]
+		if !ok {
+			return time.Time{}, 0, fmt.Errorf("%q is no weekday", words[1])
+		}
+		weeks := map[string]int{"last": -1, "this": 0, "next": 1}[first]
+		return p.inWeekOf(today, wd).AddDate(0, 0, 7*weeks), 2, nil
+	}
+	if _, ok := weekdays[first]; ok {
+		return time.Time{}, 0, &AmbiguousError{Input: words[0], Hint: fmt.Sprintf("write %q or %q", "last "+first, "this "+first)}
+	}
+	// configured date layouts may contain spaces, like "02 Jan 2006"
+	for n := min(3, len(words)); n > 0; n-- {
+		for _, layout := range p.dateLayouts() {
+			if t, err := time.ParseInLocation(layout, strings.Join(words[:n], " "), now.Location()); err == nil {
+				return t, n, nil
+			}
+		}
+	}
+	return today, 0, nil
+}
+
+// inWeekOf returns the weekday wd in the week of day, weeks start on FirstWeekday.
+func (p Parser) inWeekOf(day time.Time, wd time.Weekday) time.Time {
+	start := day.AddDate(0, 0, -((int(day.Weekday()) - int(p.FirstWeekday) + 7) % 7))
+	return start.AddDate(0, 0, (int(wd)-int(p.FirstWeekday)+7)%7)
+}
+
+// clock reads a time of day like 14:00, 9am, 9:30 pm, noon or midnight.
+func (p Parser) clock(s string) (int, int, int, error) {
+	lower := strings.ToLower(s)
+	switch lower {
+	case "noon":
+		return 12, 0, 0, nil
+	case "midnight":
+		return 0, 0, 0, nil
+	}
+	if m := twelveHour.FindStringSubmatch(lower); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		minute := 0
+		if m[2] != "" {
+			minute, _ = strconv.Atoi(m[2])
+		}
+		if h < 1 || h > 12 || minute > 59 {
+			return 0, 0, 0, fmt.Errorf("%q is no time of day", s)
+		}
+		h %= 12
+		if m[3] == "pm" {
+			h += 12
+		}
+		return h, minute, 0, nil
+	}
+	for _, layout := range p.timeLayouts() {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Hour(), t.Minute(), t.Second(), nil
+		}
+	}
+	if h, err := strconv.Atoi(lower); err == nil && h >= 0 && h < 24 {
+		if h >= 1 && h <= 12 {
+			return 0, 0, 0, &AmbiguousError{Input: s, Hint: fmt.Sprintf("write %dam, %dpm or %02d:00", h, h, h)}
+		}
+		return 0, 0, 0, &AmbiguousError{Input: s, Hint: fmt.Sprintf("write %02d:00", h)}
+	}
+	return 0, 0, 0, fmt.Errorf("%q is no time of day like 14:00 or 9am", s)
+}
+
+func (p Parser) dateLayouts() []string {
+	return nonEmpty(p.DateLayout, "2006-01-02")
+}
+
+func (p Parser) timeLayouts() []string {
+	return nonEmpty(p.TimeLayout, "15:04:05", "15:04")
+}
+
+func (p Parser) dateTimeLayouts() []string {
+	var layouts []string
+	for _, d := range p.dateLayouts() {
+		for _, t := range p.timeLayouts() {
+			layouts = append(layouts, d+" "+t)
+		}
+	}
+	return layouts
+}
+
+func nonEmpty(layouts ...string) []string {
+	var out []string
+	for _, l := range layouts {
+		if l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,229 @@
+package timeexpr
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	berlin, _ = time.LoadLocation("Europe/Berlin")
+	parser    = Parser{Location: berlin, FirstWeekday: time.Monday, DateLayout: "02.01.2006", TimeLayout: "15:04"}
+	// a Wednesday
+	now = time.Date(2024, time.March, 13, 16, 45, 0, 0, berlin)
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, berlin)
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"now", now},
+		{"14:00", at(13, 14, 0)},
+		{"9am", at(13, 9, 0)},
+		{"9:30 pm", at(13, 21, 30)},
+		{"12am", at(13, 0, 0)},
+		{"noon", at(13, 12, 0)},
+		{"yesterday 14:00", at(12, 14, 0)},
+		{"tomorrow 9am", at(14, 9, 0)},
+		{"last friday 9am", at(8, 9, 0)},
+		{"this friday 9am", at(15, 9, 0)},
+		{"next monday 08:15", at(18, 8, 15)},
+		{"this monday 9am", at(11, 9, 0)},
+		{"2h ago", now.Add(-2 * time.Hour)},
+		{"1h 30m ago", now.Add(-90 * time.Minute)},
+		{"in 15m", now.Add(15 * time.Minute)},
+		{"2024-03-01 14:00", at(1, 14, 0)},
+		{"01.03.2024 14:00", at(1, 14, 0)},
+		{"01.03.2024 9am", at(1, 9, 0)},
+		{"2024-03-01T14:00:00+01:00", at(1, 14, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parser.Time(tt.in, now, now)
+		if err != nil {
+			t.Errorf("Time(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Time(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTimeOnDay(t *testing.T) {
+	got, err := parser.Time("10:00", at(1, 0, 0), now)
+	if err != nil || !got.Equal(at(1, 10, 0)) {
+		t.Errorf("Time(10:00) on March 1st = %s, %v", got, err)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		in         string
+		start, end time.Time
+	}{
+		{"14:00-15:30", at(13, 14, 0), at(13, 15, 30)},
+		{"yesterday 14:00-15:30", at(12, 14, 0), at(12, 15, 30)},
+		{"yesterday 14:00 - 15:30", at(12, 14, 0), at(12, 15, 30)},
+		{"9am for 1h30m", at(13, 9, 0), at(13, 10, 30)},
+		{"yesterday 22:00-01:00", at(12, 22, 0), at(13, 1, 0)},
+		{"yesterday 11pm-1am", at(12, 23, 0), at(13, 1, 0)},
+		{"2024-03-01 14:00-2024-03-01 15:00", at(1, 14, 0), at(1, 15, 0)},
+		{"last friday 9am-yesterday 9am", at(8, 9, 0), at(12, 9, 0)},
+	}
+	for _, tt := range tests {
+		start, end, err := parser.Range(tt.in, now, now)
+		if err != nil {
+			t.Errorf("Range(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Range(%q) = %s - %s, want %s - %s", tt.in, start, end, tt.start, tt.end)
+		}
+	}
+	if _, _, err := parser.Range("yesterday 9am-today 8am", now, now); err != nil {
+		t.Errorf("Range over two days failed: %v", err)
+	}
+	if _, _, err := parser.Range("today 9am-yesterday 8am", now, now); err == nil {
+		t.Error("Range ending on the day before succeeded")
+	}
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		in    string
+		start time.Time
+		want  time.Time
+	}{
+		{"15:30", at(12, 14, 0), at(12, 15, 30)},
+		{"01:00", at(12, 22, 0), at(13, 1, 0)},
+		{"yesterday 13:00", at(13, 9, 0), at(12, 13, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parser.End(tt.in, tt.start, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("End(%q) after %s = %s, %v, want %s", tt.in, tt.start, got, err, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"1h 30m", 90 * time.Minute},
+		{"90m", 90 * time.Minute},
+		{"1.5h", 90 * time.Minute},
+		{"1:30", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parser.Duration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Duration(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCorrection(t *testing.T) {
+	start := at(13, 9, 0)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"-10m", at(13, 8, 50)},
+		{"+5m", at(13, 9, 5)},
+		{"08:30", at(13, 8, 30)},
+	}
+	for _, tt := range tests {
+		got, err := parser.Correction(tt.in, start, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Correction(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDay(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", at(13, 0, 0)},
+		{"yesterday", at(12, 0, 0)},
+		{"last monday", at(4, 0, 0)},
+		{"2024-03-01", at(1, 0, 0)},
+		{"72h", now.Add(-72 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parser.Day(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Day(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestAmbiguous(t *testing.T) {
+	for _, in := range []string{"friday 9am", "9", "yesterday 9", "yesterday", "last friday", "2h"} {
+		_, err := parser.Time(in, now, now)
+		var amb *AmbiguousError
+		if !errors.As(err, &amb) {
+			t.Errorf("Time(%q) = %v, want an ambiguous error", in, err)
+		}
+	}
+	if _, err := parser.Duration("90"); !errors.As(err, new(*AmbiguousError)) {
+		t.Errorf("Duration(90) = %v, want an ambiguous error", err)
+	}
+	for _, in := range []string{"", "later", "25:00", "13pm"} {
+		if _, err := parser.Time(in, now, now); err == nil || errors.As(err, new(*AmbiguousError)) {
+			t.Errorf("Time(%q) = %v, want an invalid time error", in, err)
+		}
+	}
+}
+
+func TestTrailing(t *testing.T) {
+	end := at(12, 15, 30)
+	tests := []struct {
+		in   string
+		rest string
+		span *Span
+	}{
+		{"standup #meeting yesterday 14:00-15:30", "standup #meeting", &Span{Start: at(12, 14, 0), End: &end}},
+		{"review @acme 2h ago", "review @acme", &Span{Start: now.Add(-2 * time.Hour)}},
+		{"standup at 9am", "standup", &Span{Start: at(13, 9, 0)}},
+		{"review since yesterday 14:00", "review", &Span{Start: at(12, 14, 0)}},
+		{"fix bug 42", "fix bug 42", nil},
+		{"plan tomorrow", "plan tomorrow", nil},
+		{"review friday", "review friday", nil},
+		{"prepare next friday", "prepare next friday", nil},
+		{"look at this", "look at this", nil},
+		{"now", "now", nil},
+	}
+	for _, tt := range tests {
+		rest, span, err := parser.Trailing(tt.in, now)
+		if err != nil {
+			t.Errorf("Trailing(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if rest != tt.rest {
+			t.Errorf("Trailing(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+		switch {
+		case (span == nil) != (tt.span == nil):
+			t.Errorf("Trailing(%q) = %v, want %v", tt.in, span, tt.span)
+		case span == nil:
+		case !span.Start.Equal(tt.span.Start) || (span.End == nil) != (tt.span.End == nil) ||
+			span.End != nil && !span.End.Equal(*tt.span.End):
+			t.Errorf("Trailing(%q) = %v, want %v", tt.in, span, tt.span)
+		}
+	}
+	for _, in := range []string{"meeting friday 9am", "meeting at 5", "review since yesterday"} {
+		if _, _, err := parser.Trailing(in, now); !errors.As(err, new(*AmbiguousError)) {
+			t.Errorf("Trailing(%q) = %v, want an ambiguous error", in, err)
+		}
+	}
+}